@@ -0,0 +1,232 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"encoding/json" // json
+	"runtime"       // runtime
+	"sort"          // sort
+	"strconv"       // strconv
+	"strings"       // strings
+	"time"          // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// Keys of Google Cloud Logging special fields
+const (
+	gcpTrace  string = "logging.googleapis.com/trace"          // trace
+	gcpSpan   string = "logging.googleapis.com/spanId"         // span id
+	gcpSource string = "logging.googleapis.com/sourceLocation" // source location
+)
+
+// Defaults for cloud formats
+const (
+	// Maximum depth of the call stack searched for the source location
+	sourceDepth int = 32
+)
+
+// gcpSeverities holds the Google Cloud Logging severity of each log level, indexed by the log level.
+var gcpSeverities = [...]string{
+	TraceLevel: "DEBUG",
+	DebugLevel: "DEBUG",
+	InfoLevel:  "INFO",
+	WarnLevel:  "WARNING",
+	ErrorLevel: "ERROR",
+	FatalLevel: "CRITICAL",
+}
+
+// pkgPrefix holds the prefix of the function names of the package, e.g. github.com/thorstenrie/tslog.
+var pkgPrefix = func() string {
+	// Retrieve the function name of this function
+	pc, _, _, _ := runtime.Caller(0)
+	n := runtime.FuncForPC(pc).Name()
+	// Return the function name up to the dot after the last slash
+	i := strings.LastIndex(n, "/")
+	return n[:i+strings.Index(n[i:], ".")+1]
+}()
+
+// GCPArgs contains the configuration of a Google Cloud Logging encoder.
+type GCPArgs struct {
+	Project string // project id for the trace resource name, trace id only if empty
+	Source  bool   // encode the source location of the caller
+}
+
+// AWSArgs contains the configuration of an AWS CloudWatch encoder.
+type AWSArgs struct {
+	Namespace  string            // CloudWatch metric namespace
+	Metrics    map[string]string // metric field names with unit, e.g. "latency": "Milliseconds"
+	Dimensions []string          // dimension field names
+}
+
+// NewGCPEncoder returns an Encoder for the structured logging of Google Cloud Logging with
+// configuration a. If a is nil, the defaults are used. Each entry is encoded with the fields
+// severity, message and timestamp. The log levels are mapped to the severities DEBUG for
+// Trace and Debug level, INFO, WARNING, ERROR and CRITICAL for Fatal level. Fields
+// TraceIDField and SpanIDField are encoded as logging.googleapis.com/trace and
+// logging.googleapis.com/spanId. If Source is set, the source location of the caller
+// outside of the package is encoded as logging.googleapis.com/sourceLocation. The
+// other fields are encoded as top-level fields of the JSON payload.
+func NewGCPEncoder(a *GCPArgs) Encoder {
+	// c holds a copy of the configuration
+	var c GCPArgs
+	if a != nil {
+		c = *a
+	}
+	// Return the encoder
+	return func(e *Entry) ([]byte, error) {
+		return gcpFormat(e, &c)
+	}
+}
+
+// NewAWSEncoder returns an Encoder for AWS CloudWatch Logs with configuration a. If a is nil,
+// the defaults are used. Each entry is encoded with the fields timestamp, level and message.
+// The log levels are mapped to TRACE, DEBUG, INFO, WARN, ERROR and FATAL. The fields are
+// encoded as top-level fields. If an entry contains metric fields, it is encoded in the
+// CloudWatch Embedded Metric Format with metadata _aws for the metric fields and the
+// dimension fields in the entry. It returns an error, if metrics are configured without a
+// namespace, since the Embedded Metric Format requires a namespace.
+func NewAWSEncoder(a *AWSArgs) (Encoder, error) {
+	// c holds a copy of the configuration
+	var c AWSArgs
+	if a != nil {
+		c = *a
+	}
+	// Return an error, if metrics are configured without a namespace
+	if (len(c.Metrics) > 0) && (c.Namespace == "") {
+		return nil, tserr.NotExistent("CloudWatch metric namespace")
+	}
+	// Return the encoder
+	return func(e *Entry) ([]byte, error) {
+		return awsFormat(e, &c)
+	}, nil
+}
+
+// gcpFormat encodes entry e for Google Cloud Logging with configuration c. It returns nil
+// and an error, if the log level is invalid or JSON encoding fails.
+func gcpFormat(e *Entry, c *GCPArgs) ([]byte, error) {
+	// Return nil and an error for invalid log levels
+	if _, errl := level(e.Level); errl != nil {
+		return nil, errl
+	}
+	// m holds the log message with the fields
+	m := cloudFields(e)
+	// Move the trace context from the fields to the special fields
+	if id, ok := m[TraceIDField].(string); ok && (id != "") {
+		if c.Project != "" {
+			id = "projects/" + c.Project + "/traces/" + id
+		}
+		m[gcpTrace] = id
+		delete(m, TraceIDField)
+	}
+	if id, ok := m[SpanIDField].(string); ok && (id != "") {
+		m[gcpSpan] = id
+		delete(m, SpanIDField)
+	}
+	// Set the source location of the caller, if requested and found
+	if c.Source {
+		if fr, ok := caller(); ok {
+			m[gcpSource] = map[string]string{"file": fr.File, "line": strconv.Itoa(fr.Line), "function": fr.Function}
+		}
+	}
+	// Set the fields of the log message
+	m["severity"] = gcpSeverities[e.Level]
+	m["message"] = e.Message
+	m["timestamp"] = e.Time.Format(time.RFC3339Nano)
+	// Return the JSON encoding of m
+	return cloudMarshal(m, e)
+}
+
+// awsFormat encodes entry e for AWS CloudWatch Logs with configuration c. It returns nil
+// and an error, if the log level is invalid or JSON encoding fails.
+func awsFormat(e *Entry, c *AWSArgs) ([]byte, error) {
+	// Retrieve string representation for log level
+	ls, errl := level(e.Level)
+	// Return nil and an error for invalid log levels
+	if errl != nil {
+		return nil, errl
+	}
+	// m holds the log message with the fields
+	m := cloudFields(e)
+	// metrics holds the metric definitions of the metric fields in e
+	metrics := make([]map[string]string, 0, len(c.Metrics))
+	for name, unit := range c.Metrics {
+		if _, ok := m[name]; ok {
+			metrics = append(metrics, map[string]string{"Name": name, "Unit": unit})
+		}
+	}
+	// Set the metadata of the Embedded Metric Format, if e contains metric fields
+	if len(metrics) > 0 {
+		// Sort the metric definitions by name
+		sort.Slice(metrics, func(i, j int) bool { return metrics[i]["Name"] < metrics[j]["Name"] })
+		// dims holds the dimension fields in e
+		dims := make([]string, 0, len(c.Dimensions))
+		for _, d := range c.Dimensions {
+			if _, ok := m[d]; ok {
+				dims = append(dims, d)
+			}
+		}
+		// Set the metadata
+		m["_aws"] = map[string]any{
+			"Timestamp": e.Time.UnixMilli(),
+			"CloudWatchMetrics": []map[string]any{{
+				"Namespace":  c.Namespace,
+				"Dimensions": [][]string{dims},
+				"Metrics":    metrics,
+			}},
+		}
+	}
+	// Set the fields of the log message
+	m["timestamp"] = e.Time.UTC().Format(time.RFC3339Nano)
+	m["level"] = strings.ToUpper(ls)
+	m["message"] = e.Message
+	// Return the JSON encoding of m
+	return cloudMarshal(m, e)
+}
+
+// cloudFields returns a copy of the fields of entry e with space for the fields of the log message.
+func cloudFields(e *Entry) map[string]any {
+	// Copy the fields
+	m := make(map[string]any, len(e.Fields)+4)
+	for k, v := range e.Fields {
+		m[k] = v
+	}
+	// Return the copy
+	return m
+}
+
+// cloudMarshal returns the JSON encoding of log message m of entry e. It returns nil and
+// an error, if JSON encoding fails.
+func cloudMarshal(m map[string]any, e *Entry) ([]byte, error) {
+	// Retrieve the JSON encoding of m
+	j, errj := json.Marshal(m)
+	// Return nil and an error, if JSON encoding fails
+	if errj != nil {
+		return nil, tserr.Op(&tserr.OpArgs{Op: "JSON Marshal", Fn: e.Message, Err: errj})
+	}
+	// Return the JSON encoded log message and nil
+	return j, nil
+}
+
+// caller returns the first frame of the call stack outside of the package. Frames of tests
+// of the package are considered outside. It returns false, if no frame is found.
+func caller() (runtime.Frame, bool) {
+	// Retrieve the call stack without runtime.Callers and caller
+	var pc [sourceDepth]uintptr
+	n := runtime.Callers(2, pc[:])
+	frames := runtime.CallersFrames(pc[:n])
+	// Iterate the frames
+	for {
+		fr, more := frames.Next()
+		// Return the frame, if it is outside of the package
+		if (fr.Function != "") && (!strings.HasPrefix(fr.Function, pkgPrefix) || strings.HasSuffix(fr.File, "_test.go")) {
+			return fr, true
+		}
+		// Return false, if no frames remain
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"encoding/json" // json
+	"fmt"           // fmt
+	"strings"       // strings
+	"testing"       // testing
+	"time"          // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// TestGCPEncoder logs an entry with trace context and a field with a Google Cloud Logging
+// encoder with project and source location. The test fails if the fields do not match.
+func TestGCPEncoder(t *testing.T) {
+	// Log an entry at Warn level with trace context and a field
+	m := testCloud(t, NewGCPEncoder(&GCPArgs{Project: "p", Source: true}), WarnLevel, testOTelContext)
+	// Iterate expected fields
+	for k, want := range map[string]any{"severity": "WARNING", "message": "test", gcpTrace: "projects/p/traces/" + testTraceID, gcpSpan: testSpanID, "tenant": "a"} {
+		// Record an error, if the field does not match
+		if m[k] != want {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: fmt.Sprint(want), Y: fmt.Sprint(m[k])}))
+		}
+	}
+	// Record an error, if the timestamp is not in RFC 3339 format
+	if _, err := time.Parse(time.RFC3339Nano, fmt.Sprint(m["timestamp"])); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Parse timestamp", Fn: fmt.Sprint(m["timestamp"]), Err: err}))
+	}
+	// Record an error, if the source location is not the caller in the tests
+	if src, ok := m[gcpSource].(map[string]any); !ok || !strings.HasSuffix(fmt.Sprint(src["file"]), "_test.go") {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "_test.go", Y: fmt.Sprint(m[gcpSource])}))
+	}
+}
+
+// TestGCPSeverities encodes entries of all log levels for Google Cloud Logging. The test
+// fails if a severity does not match.
+func TestGCPSeverities(t *testing.T) {
+	// Iterate log levels and their expected severities
+	for lvl, want := range map[int]string{TraceLevel: "DEBUG", DebugLevel: "DEBUG", InfoLevel: "INFO", WarnLevel: "WARNING", ErrorLevel: "ERROR", FatalLevel: "CRITICAL"} {
+		// Encode an entry at level lvl
+		j, err := gcpFormat(&Entry{Level: lvl, Message: "test"}, &GCPArgs{})
+		// Stop execution, if encoding fails
+		if err != nil {
+			t.Fatal(tserr.Op(&tserr.OpArgs{Op: "gcpFormat", Fn: want, Err: err}))
+		}
+		// Record an error, if the severity does not match
+		if !strings.Contains(string(j), `"severity":"`+want+`"`) {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: want, Y: string(j)}))
+		}
+	}
+}
+
+// TestAWSEncoder logs entries with and without a metric field with an AWS CloudWatch encoder.
+// The test fails if the entry with the metric field is not in the Embedded Metric Format or the
+// entry without the metric field contains EMF metadata.
+func TestAWSEncoder(t *testing.T) {
+	// Create the encoder with a metric and a dimension
+	enc, err := NewAWSEncoder(&AWSArgs{Namespace: "app", Metrics: map[string]string{"latency": "Milliseconds"}, Dimensions: []string{"service"}})
+	// Stop execution, if creating the encoder fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New AWS encoder", Fn: "app", Err: err}))
+	}
+	// Log an entry at Info level with a metric field and a dimension field
+	m := testCloud(t, enc, InfoLevel, func(e *Entry) bool {
+		e.SetField("latency", 12)
+		e.SetField("service", "api")
+		return true
+	})
+	// Iterate expected fields
+	for k, want := range map[string]any{"level": "INFO", "message": "test", "latency": float64(12), "service": "api"} {
+		// Record an error, if the field does not match
+		if m[k] != want {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: fmt.Sprint(want), Y: fmt.Sprint(m[k])}))
+		}
+	}
+	// Record an error, if the EMF metadata does not match
+	want := `map[CloudWatchMetrics:[map[Dimensions:[[service]] Metrics:[map[Name:latency Unit:Milliseconds]] Namespace:app]]`
+	if aws := fmt.Sprint(m["_aws"]); !strings.HasPrefix(aws, want) {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: want, Y: aws}))
+	}
+	// Log an entry at Fatal level without a metric field
+	m = testCloud(t, enc, FatalLevel, func(*Entry) bool { return true })
+	// Record an error, if the entry contains EMF metadata
+	if _, ok := m["_aws"]; ok {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "no _aws", Y: fmt.Sprint(m)}))
+	}
+	// Record an error, if the level does not match
+	if m["level"] != "FATAL" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "FATAL", Y: fmt.Sprint(m["level"])}))
+	}
+}
+
+// TestAWSEncoderErr creates AWS CloudWatch encoders with metrics without a namespace and with
+// neither metrics nor a namespace. The test fails if only the first returns an error.
+func TestAWSEncoderErr(t *testing.T) {
+	// Record an error, if NewAWSEncoder returns nil for metrics without a namespace
+	if _, err := NewAWSEncoder(&AWSArgs{Metrics: map[string]string{"latency": "Milliseconds"}}); err == nil {
+		t.Error(tserr.NilFailed("New AWS encoder"))
+	}
+	// Record an error, if NewAWSEncoder returns an error without metrics
+	if _, err := NewAWSEncoder(nil); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "New AWS encoder", Fn: "nil", Err: err}))
+	}
+}
+
+// TestCloudFormats sets the cloud formats. The test fails if a format is not available.
+func TestCloudFormats(t *testing.T) {
+	// Iterate the cloud formats
+	for _, f := range []Format{GCPFormat, AWSFormat} {
+		// Record an error, if setting the format fails
+		if err := New().SetFormat(f); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "SetFormat", Fn: string(f), Err: err}))
+		}
+	}
+}
+
+// testCloud creates a logger logging to a temporary file with encoder enc and processor p and
+// logs an entry with message "test" at level lvl. It returns the decoded log message.
+func testCloud(t *testing.T, enc Encoder, lvl int, p Processor) map[string]any {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Create new logger lg logging to the temporary file fn
+	lg, fn := traceLogger(t)
+	// Set the encoder and the processor
+	lg.SetEncoder(enc)
+	lg.AddProcessor(p)
+	// Log an entry at level lvl
+	if err := testLogger(&testcase{level: lvl, in: "test"}, lg); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Log", Fn: string(fn), Err: err}))
+	}
+	// Retrieve the log message
+	fs := scanner(t, fn)
+	rm(t, fn)
+	if !fs.Scan() {
+		t.Fatal(tserr.NilFailed("Scan"))
+	}
+	// Decode the log message
+	var m map[string]any
+	if err := json.Unmarshal(fs.Bytes(), &m); err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "JSON Unmarshal", Fn: fs.Text(), Err: err}))
+	}
+	// Return the decoded log message
+	return m
+}
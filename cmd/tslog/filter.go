@@ -0,0 +1,185 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package main
+
+// Import standard library packages, tserr and tslog.
+import (
+	"flag"    // flag
+	"fmt"     // fmt
+	"regexp"  // regexp
+	"strings" // strings
+	"time"    // time
+
+	"github.com/thorstenrie/tserr" // tserr
+	"github.com/thorstenrie/tslog" // tslog
+)
+
+// Layouts of time range flags
+var timeLayouts = [...]string{time.RFC3339Nano, "2006-01-02 15:04:05 -0700 MST", "2006-01-02 15:04:05", "2006-01-02"}
+
+// filter selects entries by minimum level, time range, message and field values.
+type filter struct {
+	lvl      levelFlag  // minimum log level
+	since    timeFlag   // earliest timestamp, any if zero
+	until    timeFlag   // latest timestamp, any if zero
+	contains string     // message substring
+	match    regexpFlag // message regular expression
+	fields   fieldsFlag // field values
+}
+
+// levelFlag implements flag.Value for the minimum log level of a filter.
+type levelFlag int
+
+// timeFlag implements flag.Value for a timestamp of a time range.
+type timeFlag time.Time
+
+// regexpFlag implements flag.Value for a regular expression.
+type regexpFlag struct {
+	re *regexp.Regexp // regular expression, nil if not set
+}
+
+// fieldsFlag implements flag.Value for field values, e.g. "tenant=a".
+type fieldsFlag map[string]string
+
+// register registers the flags level, since, until, contains, match and field of filter f on fs.
+func (f *filter) register(fs *flag.FlagSet) {
+	// Select entries from Trace level by default
+	f.lvl = levelFlag(tslog.TraceLevel)
+	f.fields = make(fieldsFlag)
+	// Register the flags
+	fs.Var(&f.lvl, "level", "minimum log level, e.g. info or 3")
+	fs.Var(&f.since, "since", "earliest timestamp in RFC 3339 format or 2006-01-02 15:04:05")
+	fs.Var(&f.until, "until", "latest timestamp in RFC 3339 format or 2006-01-02 15:04:05")
+	fs.StringVar(&f.contains, "contains", "", "message substring")
+	fs.Var(&f.match, "match", "message regular expression")
+	fs.Var(f.fields, "field", "field value as key=value, may be repeated")
+}
+
+// selects returns true, if entry e is selected by filter f.
+func (f *filter) selects(e *tslog.Entry) bool {
+	// Return false, if the level of e is below the minimum level
+	if e.Level < int(f.lvl) {
+		return false
+	}
+	// Return false, if the timestamp of e is out of the time range
+	if s := time.Time(f.since); !s.IsZero() && e.Time.Before(s) {
+		return false
+	}
+	if u := time.Time(f.until); !u.IsZero() && e.Time.After(u) {
+		return false
+	}
+	// Return false, if the message of e does not match
+	if !strings.Contains(e.Message, f.contains) {
+		return false
+	}
+	if (f.match.re != nil) && !f.match.re.MatchString(e.Message) {
+		return false
+	}
+	// Return false, if a field of e does not match
+	for k, v := range f.fields {
+		if fv, ok := e.Fields[k]; !ok || (fmt.Sprint(fv) != v) {
+			return false
+		}
+	}
+	// Return true
+	return true
+}
+
+// String returns the string representation of the minimum log level.
+func (f *levelFlag) String() string {
+	// Return an empty string for the zero value
+	if (f == nil) || (*f == 0) {
+		return ""
+	}
+	// Return the string representation
+	return fmt.Sprint(int(*f))
+}
+
+// Set sets the minimum log level to s. It returns an error, if s is not a defined log level.
+func (f *levelFlag) Set(s string) error {
+	// Parse the log level
+	lvl, err := tslog.ParseLevel(s)
+	// Return an error, if parsing fails
+	if err != nil {
+		return err
+	}
+	// Set the minimum log level
+	*f = levelFlag(lvl)
+	// Return nil
+	return nil
+}
+
+// String returns the timestamp in RFC 3339 format or an empty string, if it is not set.
+func (f *timeFlag) String() string {
+	// Return an empty string for the zero value
+	if (f == nil) || time.Time(*f).IsZero() {
+		return ""
+	}
+	// Return the timestamp
+	return time.Time(*f).Format(time.RFC3339Nano)
+}
+
+// Set sets the timestamp to s in one of the time layouts. It returns an error, if s does not
+// match any of the time layouts.
+func (f *timeFlag) Set(s string) error {
+	// Iterate the time layouts
+	for _, layout := range timeLayouts {
+		// Set the timestamp, if s matches the layout in local time
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			*f = timeFlag(t)
+			return nil
+		}
+	}
+	// Return an error, if s does not match any time layout
+	return tserr.NotExistent(fmt.Sprintf("time layout for %s", s))
+}
+
+// String returns the regular expression or an empty string, if it is not set.
+func (f *regexpFlag) String() string {
+	// Return an empty string for the zero value
+	if (f == nil) || (f.re == nil) {
+		return ""
+	}
+	// Return the regular expression
+	return f.re.String()
+}
+
+// Set sets the regular expression to s. It returns an error, if s is not a valid regular expression.
+func (f *regexpFlag) Set(s string) error {
+	// Compile the regular expression
+	re, err := regexp.Compile(s)
+	// Return an error, if compiling fails
+	if err != nil {
+		return tserr.Op(&tserr.OpArgs{Op: "compile", Fn: s, Err: err})
+	}
+	// Set the regular expression
+	f.re = re
+	// Return nil
+	return nil
+}
+
+// String returns the field values as comma separated list of key=value.
+func (f fieldsFlag) String() string {
+	// kv holds the field values
+	kv := make([]string, 0, len(f))
+	for k, v := range f {
+		kv = append(kv, k+"="+v)
+	}
+	// Return the comma separated list
+	return strings.Join(kv, ",")
+}
+
+// Set adds field value s in the form key=value. It returns an error, if s does not contain "=".
+func (f fieldsFlag) Set(s string) error {
+	// Split s into key and value
+	k, v, ok := strings.Cut(s, "=")
+	// Return an error, if s does not contain "="
+	if !ok || (k == "") {
+		return tserr.NotExistent(fmt.Sprintf("key=value in %s", s))
+	}
+	// Add the field value
+	f[k] = v
+	// Return nil
+	return nil
+}
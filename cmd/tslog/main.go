@@ -0,0 +1,160 @@
+// Command tslog pretty-prints and filters log messages in JSON format written by tslog.
+//
+// Usage:
+//
+//	tslog [flags] [file ...]
+//	tslog tail [-f] [-n lines] [flags] file
+//	tslog merge [flags] file ...
+//
+// It reads the log messages from the files or from Stdin, if no file or "-" is given,
+// and writes the selected entries human-readable to Stdout. With -json, the selected
+// log messages are re-emitted unchanged for piping. Malformed lines are reported to
+// Stderr with their line number and skipped.
+//
+// Subcommand tail writes the selected entries of the last lines of a file. With -f, it
+// follows the file like tail -F until interrupted. If the file is truncated, it continues
+// from its beginning. If the file is rotated by renaming, it reads the rest of the renamed
+// file and continues with the new file.
+//
+// Subcommand merge merges the selected entries of multiple files chronologically by
+// timestamp. Entries with equal timestamps are written in the order of the files. Each
+// entry is tagged with its file, with -json in field source.
+//
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package main
+
+// Import standard library packages, tserr and tslog.
+import (
+	"context"   // context
+	"errors"    // errors
+	"flag"      // flag
+	"fmt"       // fmt
+	"io"        // io
+	"os"        // os
+	"os/signal" // signal
+
+	"github.com/thorstenrie/tserr" // tserr
+	"github.com/thorstenrie/tslog" // tslog
+)
+
+// Name of the command and the input from Stdin
+const (
+	command string = "tslog" // name of the command
+	stdin   string = "-"     // filename for Stdin
+)
+
+// main runs the command with the arguments and exits with its exit code.
+func main() {
+	// Stop following files on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	// Run the command
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	// Exit with the exit code
+	os.Exit(code)
+}
+
+// run runs the command with arguments args, reading from in if no file is given and writing
+// to out and errors to eout. Subcommand tail runs until ctx is done. It returns 0 on success,
+// 1 if a file cannot be read or written, and 2 for invalid arguments.
+func run(ctx context.Context, args []string, in io.Reader, out, eout io.Writer) int {
+	// Run subcommand tail or merge, if requested
+	if (len(args) > 0) && (args[0] == tailCommand) {
+		return runTail(ctx, args[1:], out, eout)
+	}
+	if (len(args) > 0) && (args[0] == mergeCommand) {
+		return runMerge(args[1:], out, eout)
+	}
+	// Create the flag set with the filter and the printer
+	fs, f, p := flags(command, "[flags] [file ...]", out, eout)
+	// Return 2, if parsing the arguments fails
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	// Read from Stdin, if no file is given
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{stdin}
+	}
+	// code holds the exit code
+	code := 0
+	// View each file
+	for _, fn := range files {
+		if err := viewFile(fn, in, f, p, eout); err != nil {
+			fmt.Fprintf(eout, "%s: %v\n", command, err)
+			code = 1
+		}
+	}
+	// Return the exit code
+	return code
+}
+
+// flags returns a new flag set for the command name with usage of the arguments, writing
+// errors to eout, and a filter and a printer to out with their flags registered.
+func flags(name, usage string, out, eout io.Writer) (*flag.FlagSet, *filter, *printer) {
+	// Create the flag set
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(eout)
+	fs.Usage = func() {
+		fmt.Fprintf(eout, "Usage: %s %s\n", name, usage)
+		fs.PrintDefaults()
+	}
+	// Register the flags of the filter and the printer
+	f, p := &filter{}, &printer{w: out}
+	f.register(fs)
+	fs.BoolVar(&p.raw, "json", false, "re-emit the selected log messages as raw JSON")
+	// Return the flag set, the filter and the printer
+	return fs, f, p
+}
+
+// viewFile writes the entries of file fn selected by filter f with printer p. If fn is "-",
+// it reads from in. Malformed lines are reported to eout. It returns an error, if fn cannot
+// be opened or reading or writing fails.
+func viewFile(fn string, in io.Reader, f *filter, p *printer, eout io.Writer) error {
+	// Read from in, if fn is Stdin
+	if fn == stdin {
+		return view(in, fn, f, p, eout)
+	}
+	// Open file fn
+	r, err := os.Open(fn)
+	if err != nil {
+		return tserr.Op(&tserr.OpArgs{Op: "open", Fn: fn, Err: err})
+	}
+	// Close file fn on return
+	defer r.Close()
+	// View the entries of fn
+	return view(r, fn, f, p, eout)
+}
+
+// view writes the entries of input r named fn selected by filter f with printer p. Malformed
+// lines are reported to eout and skipped. It returns an error, if reading or writing fails.
+func view(r io.Reader, fn string, f *filter, p *printer, eout io.Writer) error {
+	// Create the reader
+	lr := tslog.NewReader(r)
+	for {
+		// Read the next entry
+		e, err := lr.Read()
+		// Return nil at the end of the input
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		// Report a malformed line and continue with the next line
+		var perr *tslog.ParseError
+		if errors.As(err, &perr) {
+			fmt.Fprintf(eout, "%s: %s: %v\n", command, fn, err)
+			continue
+		}
+		// Return an error, if reading fails
+		if err != nil {
+			return tserr.Op(&tserr.OpArgs{Op: "read", Fn: fn, Err: err})
+		}
+		// Write the entry, if it is selected
+		if f.selects(e) {
+			if err := p.print(e, lr.Bytes(), ""); err != nil {
+				return tserr.Op(&tserr.OpArgs{Op: "write", Fn: fn, Err: err})
+			}
+		}
+	}
+}
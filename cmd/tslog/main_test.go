@@ -0,0 +1,117 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package main
+
+// Import standard library packages and tserr.
+import (
+	"bytes"         // bytes
+	"context"       // context
+	"fmt"           // fmt
+	"os"            // os
+	"path/filepath" // filepath
+	"strings"       // strings
+	"testing"       // testing
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// testInput holds log messages with fields, a malformed line and an empty line.
+var testInput = strings.Join([]string{
+	`{"log":{"level":"debug","message":"start","time":"2023-01-02 15:04:05 +0000 UTC"}}`,
+	`{"log":{"level":"info","message":"request done","time":"2023-01-02 15:04:06 +0000 UTC","fields":{"tenant":"a","ms":12}}}`,
+	`malformed`,
+	``,
+	`{"log":{"level":"error","message":"request failed","time":"2023-01-02 15:04:07 +0000 UTC","fields":{"tenant":"b b"}}}`,
+}, "\n")
+
+// TestRun runs the command with filters on Stdin. The test fails if the output does not
+// contain the selected entries or the malformed line is not reported.
+func TestRun(t *testing.T) {
+	// Iterate arguments and the expected output
+	for _, c := range []struct {
+		args []string // arguments
+		want string   // expected output
+	}{
+		{nil, "2023-01-02 15:04:05 +0000 DEBUG start\n2023-01-02 15:04:06 +0000 INFO  request done ms=12 tenant=a\n2023-01-02 15:04:07 +0000 ERROR request failed tenant=\"b b\"\n"},
+		{[]string{"-level", "info", "-contains", "request", "-field", "tenant=a"}, "2023-01-02 15:04:06 +0000 INFO  request done ms=12 tenant=a\n"},
+		{[]string{"-match", "fail(ed)?$", "-json"}, strings.Split(testInput, "\n")[4] + "\n"},
+		{[]string{"-since", "2023-01-02T15:04:06Z", "-until", "2023-01-02T15:04:06Z", "-"}, "2023-01-02 15:04:06 +0000 INFO  request done ms=12 tenant=a\n"},
+	} {
+		// Run the command reading testInput from Stdin
+		out, eout, code := testRun(c.args, testInput)
+		// Record an error, if the command fails
+		if code != 0 {
+			t.Error(tserr.Equal(&tserr.EqualArgs{Var: "exit code", Actual: int64(code), Want: 0}))
+		}
+		// Record an error, if the output does not match
+		if out != c.want {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: c.want, Y: out}))
+		}
+		// Record an error, if the malformed line is not reported
+		if !strings.Contains(eout, "line 3") {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "line 3", Y: eout}))
+		}
+	}
+}
+
+// TestRunFile runs the command on a file and a missing file. The test fails if the entries of
+// the file are not written or the exit code is not 1.
+func TestRunFile(t *testing.T) {
+	// Write testInput to a file
+	fn := filepath.Join(t.TempDir(), "test.log")
+	if err := os.WriteFile(fn, []byte(testInput), 0600); err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "WriteFile", Fn: fn, Err: err}))
+	}
+	// Run the command on the file and a missing file
+	out, eout, code := testRun([]string{"-level", "error", fn, fn + ".missing"}, "")
+	// Record an error, if the exit code is not 1
+	if code != 1 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "exit code", Actual: int64(code), Want: 1}))
+	}
+	// Record an error, if the entry of the file is not written
+	if !strings.Contains(out, "request failed") {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "request failed", Y: out}))
+	}
+	// Record an error, if the missing file is not reported
+	if !strings.Contains(eout, ".missing") {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: ".missing", Y: eout}))
+	}
+}
+
+// TestRunDir runs the command on a directory. The test fails if the read error is not
+// reported once or the exit code is not 1.
+func TestRunDir(t *testing.T) {
+	// Run the command on a directory
+	_, eout, code := testRun([]string{t.TempDir()}, "")
+	// Record an error, if the exit code is not 1
+	if code != 1 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "exit code", Actual: int64(code), Want: 1}))
+	}
+	// Record an error, if the read error is not reported once
+	if n := strings.Count(eout, "\n"); n != 1 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "reported errors", Actual: int64(n), Want: 1}))
+	}
+}
+
+// TestRunArgs runs the command with invalid arguments. The test fails if the exit code is not 2.
+func TestRunArgs(t *testing.T) {
+	// Iterate invalid arguments
+	for _, args := range [][]string{{"-level", "none"}, {"-since", "yesterday"}, {"-match", "("}, {"-field", "tenant"}} {
+		// Record an error, if the exit code is not 2
+		if _, _, code := testRun(args, ""); code != 2 {
+			t.Error(tserr.Equal(&tserr.EqualArgs{Var: fmt.Sprint(args), Actual: int64(code), Want: 2}))
+		}
+	}
+}
+
+// testRun runs the command with arguments args reading input from Stdin. It returns the
+// output, the error output and the exit code.
+func testRun(args []string, input string) (string, string, int) {
+	// out and eout hold the output and the error output
+	var out, eout bytes.Buffer
+	// Run the command
+	code := run(context.Background(), args, strings.NewReader(input), &out, &eout)
+	// Return the output, the error output and the exit code
+	return out.String(), eout.String(), code
+}
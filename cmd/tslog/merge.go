@@ -0,0 +1,80 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package main
+
+// Import standard library packages, tserr and tslog.
+import (
+	"errors" // errors
+	"fmt"    // fmt
+	"io"     // io
+	"os"     // os
+
+	"github.com/thorstenrie/tserr" // tserr
+	"github.com/thorstenrie/tslog" // tslog
+)
+
+// Name of subcommand merge
+const mergeCommand string = "merge"
+
+// runMerge runs subcommand merge with arguments args writing to out and errors to eout. It
+// returns 0 on success, 1 if a file cannot be read or written, and 2 for invalid arguments.
+func runMerge(args []string, out, eout io.Writer) int {
+	// Create the flag set with the filter and the printer
+	fs, f, p := flags(command+" "+mergeCommand, "[flags] file ...", out, eout)
+	// Return 2, if parsing the arguments fails or no file is given
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	// srcs holds the files as sources
+	srcs := make([]tslog.MergeSource, 0, fs.NArg())
+	// code holds the exit code
+	code := 0
+	// Open the files
+	for _, fn := range fs.Args() {
+		r, err := os.Open(fn)
+		if err != nil {
+			fmt.Fprintf(eout, "%s: %v\n", command, tserr.Op(&tserr.OpArgs{Op: "open", Fn: fn, Err: err}))
+			code = 1
+			continue
+		}
+		// Close the file on return
+		defer r.Close()
+		srcs = append(srcs, tslog.MergeSource{Name: fn, R: r})
+	}
+	// Merge the files
+	if err := merge(tslog.NewMerger(srcs...), f, p, eout); err != nil {
+		fmt.Fprintf(eout, "%s: %v\n", command, err)
+		code = 1
+	}
+	// Return the exit code
+	return code
+}
+
+// merge writes the entries of merger m selected by filter f tagged with their source with
+// printer p. Malformed lines are reported to eout. It returns an error, if writing fails.
+func merge(m *tslog.Merger, f *filter, p *printer, eout io.Writer) error {
+	for {
+		// Read the next entry
+		e, err := m.Read()
+		// Return nil at the end of the sources
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		// Report a malformed line and continue with the next entry
+		if err != nil {
+			fmt.Fprintf(eout, "%s: %v\n", command, err)
+			continue
+		}
+		// Write the entry, if it is selected
+		if f.selects(e.Entry) {
+			if err := p.print(e.Entry, e.Line, e.Source); err != nil {
+				return tserr.Op(&tserr.OpArgs{Op: "write", Fn: e.Source, Err: err})
+			}
+		}
+	}
+}
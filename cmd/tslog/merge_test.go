@@ -0,0 +1,68 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package main
+
+// Import standard library packages and tserr.
+import (
+	"encoding/json" // json
+	"os"            // os
+	"path/filepath" // filepath
+	"strings"       // strings
+	"testing"       // testing
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// TestMerge runs subcommand merge on two files. The test fails if the entries are not merged
+// chronologically and tagged with their file or the raw log messages are not tagged in field
+// source.
+func TestMerge(t *testing.T) {
+	// Write log messages of two services to files a and b
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	for fn, lines := range map[string]string{
+		a: `{"log":{"level":"info","message":"a1","time":"2023-01-02 15:04:05 +0000 UTC"}}` + "\n" +
+			`{"log":{"level":"info","message":"a2","time":"2023-01-02 15:04:07 +0000 UTC","fields":{"ms":12}}}` + "\n",
+		b: `{"log":{"level":"warn","message":"b1","time":"2023-01-02 16:04:06 +0100 CET"}}` + "\n",
+	} {
+		if err := os.WriteFile(fn, []byte(lines), 0600); err != nil {
+			t.Fatal(tserr.Op(&tserr.OpArgs{Op: "WriteFile", Fn: fn, Err: err}))
+		}
+	}
+	// Merge the files human-readable
+	out, eout, code := testRun([]string{mergeCommand, a, b}, "")
+	if code != 0 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "exit code", Actual: int64(code), Want: 0}))
+	}
+	// Record an error, if the entries are not merged chronologically and tagged with their file
+	want := a + " 2023-01-02 15:04:05 +0000 INFO  a1\n" + b + " 2023-01-02 16:04:06 +0100 WARN  b1\n" + a + " 2023-01-02 15:04:07 +0000 INFO  a2 ms=12\n"
+	if out != want {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: want, Y: out + eout}))
+	}
+	// Merge the files as raw JSON from Warn level
+	out, _, _ = testRun([]string{mergeCommand, "-json", "-level", "warn", a, b}, "")
+	// Record an error, if the log message is not tagged in field source
+	var m struct {
+		L struct {
+			Msg    string            `json:"message"`
+			Fields map[string]string `json:"fields"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(out)), &m); (err != nil) || (m.L.Msg != "b1") || (m.L.Fields[sourceField] != b) {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: b, Y: out}))
+	}
+}
+
+// TestMergeArgs runs subcommand merge without files and with a missing file. The test fails
+// if the exit codes are not 2 and 1.
+func TestMergeArgs(t *testing.T) {
+	// Record an error, if the exit code without files is not 2
+	if _, _, code := testRun([]string{mergeCommand}, ""); code != 2 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "exit code", Actual: int64(code), Want: 2}))
+	}
+	// Record an error, if the exit code with a missing file is not 1
+	if _, _, code := testRun([]string{mergeCommand, filepath.Join(t.TempDir(), "missing.log")}, ""); code != 1 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "exit code", Actual: int64(code), Want: 1}))
+	}
+}
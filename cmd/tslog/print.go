@@ -0,0 +1,117 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package main
+
+// Import standard library packages and tslog.
+import (
+	"encoding/json" // json
+	"fmt"           // fmt
+	"io"            // io
+	"sort"          // sort
+	"strconv"       // strconv
+	"strings"       // strings
+
+	"github.com/thorstenrie/tslog" // tslog
+)
+
+// Layout of the output
+const (
+	printLayout string = "2006-01-02 15:04:05 -0700" // timestamp in human-readable output
+	logRoot     string = "log"                       // root element of log messages in JSON format
+	logFields   string = "fields"                    // fields element of log messages in JSON format
+	sourceField string = "source"                    // field tagging a log message with its source
+)
+
+// levelNames holds the upper case name of each log level, indexed by the log level.
+var levelNames = [...]string{
+	tslog.TraceLevel: "TRACE",
+	tslog.DebugLevel: "DEBUG",
+	tslog.InfoLevel:  "INFO",
+	tslog.WarnLevel:  "WARN",
+	tslog.ErrorLevel: "ERROR",
+	tslog.FatalLevel: "FATAL",
+}
+
+// printer writes entries human-readable or as raw JSON.
+type printer struct {
+	w   io.Writer // output
+	raw bool      // re-emit the raw JSON log messages
+}
+
+// print writes entry e read from log message line to the output. If raw is set, it writes line
+// unchanged or, if source is not empty, with field source set to source. Otherwise, it writes
+// source, if not empty, the timestamp, the log level, the message and the fields sorted by key.
+// It returns an error, if writing fails.
+func (p *printer) print(e *tslog.Entry, line []byte, source string) error {
+	// Write the raw log message, if requested
+	if p.raw {
+		// Tag the log message with its source, if any
+		if source != "" {
+			line = tag(line, source)
+		}
+		_, err := fmt.Fprintf(p.w, "%s\n", line)
+		return err
+	}
+	// b holds the human-readable entry
+	var b strings.Builder
+	// Write the source, if any
+	if source != "" {
+		b.WriteString(source + " ")
+	}
+	// Write the timestamp, the log level and the message
+	fmt.Fprintf(&b, "%s %-5s %s", e.Time.Format(printLayout), levelNames[e.Level], e.Message)
+	// Retrieve the keys of the fields sorted
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	// Write the fields as key=value
+	for _, k := range keys {
+		b.WriteString(" " + k + "=" + value(e.Fields[k]))
+	}
+	// Write the human-readable entry
+	_, err := fmt.Fprintln(p.w, b.String())
+	return err
+}
+
+// tag returns log message line in JSON format with field source set to source. The other fields
+// are kept unchanged. It returns line unchanged, if it is not a log message in JSON format.
+func tag(line []byte, source string) []byte {
+	// Decode the root element and the log message
+	var root map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(line, &root); (err != nil) || (root[logRoot] == nil) {
+		return line
+	}
+	// Decode the fields, if any
+	fields := make(map[string]json.RawMessage)
+	if f, ok := root[logRoot][logFields]; ok {
+		if err := json.Unmarshal(f, &fields); err != nil {
+			return line
+		}
+	}
+	// Set field source
+	fields[sourceField], _ = json.Marshal(source)
+	root[logRoot][logFields], _ = json.Marshal(fields)
+	// Encode the log message
+	t, err := json.Marshal(root)
+	if err != nil {
+		return line
+	}
+	// Return the tagged log message
+	return t
+}
+
+// value returns the string representation of field value v. Strings containing white space
+// or quotes and empty strings are quoted.
+func value(v any) string {
+	// s holds the string representation of v
+	s := fmt.Sprint(v)
+	// Quote s, if it is a string containing white space or quotes or an empty string
+	if _, ok := v.(string); ok && ((s == "") || strings.ContainsAny(s, " \t\n\"=")) {
+		return strconv.Quote(s)
+	}
+	// Return the string representation
+	return s
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package main
+
+// Import standard library packages and tserr.
+import (
+	"context" // context
+	"errors"  // errors
+	"fmt"     // fmt
+	"io"      // io
+	"os"      // os
+	"time"    // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// Defaults for subcommand tail
+const (
+	tailCommand string        = "tail"                 // name of the subcommand
+	tailLines   int           = 10                     // number of last lines
+	tailPoll    time.Duration = 250 * time.Millisecond // poll interval for following
+	tailChunk   int64         = 4096                   // size of chunks read backwards for the last lines
+)
+
+// tailer is an io.Reader reading a file from an offset. If follow is set, it waits for new
+// data at the end of the file until ctx is done and continues with the beginning of the file,
+// if it is truncated, or with the new file, if it is rotated by renaming.
+type tailer struct {
+	ctx    context.Context // context to stop following
+	fn     string          // filename
+	f      *os.File        // current file
+	off    int64           // offset in the current file
+	follow bool            // follow the file
+	poll   time.Duration   // poll interval
+}
+
+// runTail runs subcommand tail with arguments args writing to out and errors to eout. With -f,
+// it follows the file until ctx is done. It returns 0 on success, 1 if the file cannot be read
+// or written, and 2 for invalid arguments.
+func runTail(ctx context.Context, args []string, out, eout io.Writer) int {
+	// Create the flag set with the filter and the printer
+	fs, f, p := flags(command+" "+tailCommand, "[-f] [-n lines] [flags] file", out, eout)
+	// Register the flags of the subcommand
+	t := &tailer{ctx: ctx}
+	fs.BoolVar(&t.follow, "f", false, "follow the file through truncation and rotation")
+	fs.DurationVar(&t.poll, "poll", tailPoll, "poll interval for following")
+	n := fs.Int("n", tailLines, "number of last lines to start with, all lines if negative")
+	// Return 2, if parsing the arguments fails or not exactly one file is given
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	// Open the file at the beginning of the last n lines
+	t.fn = fs.Arg(0)
+	if err := t.open(*n); err != nil {
+		fmt.Fprintf(eout, "%s: %v\n", command, err)
+		return 1
+	}
+	// Close the current file on return
+	defer func() {
+		t.f.Close()
+	}()
+	// View the entries of the file
+	if err := view(t, t.fn, f, p, eout); err != nil {
+		fmt.Fprintf(eout, "%s: %v\n", command, err)
+		return 1
+	}
+	// Return 0
+	return 0
+}
+
+// open opens the file at the beginning of the last n lines. If n is negative, it opens the
+// file at its beginning. It returns an error, if opening or reading the file fails.
+func (t *tailer) open(n int) error {
+	// Open the file
+	f, err := os.Open(t.fn)
+	if err != nil {
+		return tserr.Op(&tserr.OpArgs{Op: "open", Fn: t.fn, Err: err})
+	}
+	t.f = f
+	// Return nil, if the file is read from its beginning
+	if n < 0 {
+		return nil
+	}
+	// Retrieve the offset of the last n lines
+	off, err := lastLines(f, n)
+	if err == nil {
+		t.off, err = f.Seek(off, io.SeekStart)
+	}
+	// Return an error, if reading or seeking fails
+	if err != nil {
+		f.Close()
+		return tserr.Op(&tserr.OpArgs{Op: "read", Fn: t.fn, Err: err})
+	}
+	// Return nil
+	return nil
+}
+
+// Read reads up to len(p) bytes from the current file. If follow is set, it waits for new data
+// at the end of the file, continues with the beginning of the file, if it is truncated, and
+// continues with the new file, if it is rotated. It returns io.EOF at the end of the file, if
+// follow is not set, or when ctx is done.
+func (t *tailer) Read(p []byte) (int, error) {
+	for {
+		// Read from the current file
+		n, err := t.f.Read(p)
+		t.off += int64(n)
+		// Return the read bytes, if any
+		if n > 0 {
+			return n, nil
+		}
+		// Return an error, if reading fails or the end of the file is reached without following
+		if !errors.Is(err, io.EOF) || !t.follow {
+			return 0, err
+		}
+		// Continue, if the file is truncated or rotated
+		if t.reopen() {
+			continue
+		}
+		// Wait for new data or return io.EOF, when ctx is done
+		select {
+		case <-t.ctx.Done():
+			return 0, io.EOF
+		case <-time.After(t.poll):
+		}
+	}
+}
+
+// reopen continues with the new file, if the file is rotated, or with the beginning of the
+// current file, if it is truncated. It returns true, if the file is rotated or truncated.
+func (t *tailer) reopen() bool {
+	// Retrieve the current file
+	cur, err := t.f.Stat()
+	if err != nil {
+		return false
+	}
+	// Continue with the new file, if the file is rotated and the new file exists
+	if fi, err := os.Stat(t.fn); (err == nil) && !os.SameFile(cur, fi) {
+		if f, err := os.Open(t.fn); err == nil {
+			t.f.Close()
+			t.f, t.off = f, 0
+			return true
+		}
+	}
+	// Continue with the beginning of the current file, if it is truncated
+	if cur.Size() < t.off {
+		if _, err := t.f.Seek(0, io.SeekStart); err == nil {
+			t.off = 0
+			return true
+		}
+	}
+	// Return false
+	return false
+}
+
+// lastLines returns the offset of the last n lines of file f. A newline at the end of the
+// file does not start a line. It returns an error, if reading f fails.
+func lastLines(f *os.File, n int) (int64, error) {
+	// Retrieve the size of f
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	// Return the end of the file, if no lines are requested
+	if n == 0 {
+		return fi.Size(), nil
+	}
+	// off holds the offset of the current chunk and end the size of the file
+	off, end := fi.Size(), fi.Size()
+	// buf holds the current chunk
+	buf := make([]byte, tailChunk)
+	// Read chunks backwards until n newlines are found
+	for off > 0 {
+		// Retrieve the size and the offset of the chunk
+		size := tailChunk
+		if off < size {
+			size = off
+		}
+		off -= size
+		// Read the chunk
+		if _, err := f.ReadAt(buf[:size], off); err != nil {
+			return 0, err
+		}
+		// Search newlines backwards
+		for i := size - 1; i >= 0; i-- {
+			if buf[i] != '\n' {
+				continue
+			}
+			// Skip a newline at the end of the file
+			if off+i == end-1 {
+				continue
+			}
+			// Return the offset after the n-th newline
+			if n--; n == 0 {
+				return off + i + 1, nil
+			}
+		}
+	}
+	// Return the beginning of the file
+	return 0, nil
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package main
+
+// Import standard library packages, tserr, tsfio and tslog.
+import (
+	"bytes"         // bytes
+	"context"       // context
+	"fmt"           // fmt
+	"os"            // os
+	"path/filepath" // filepath
+	"strings"       // strings
+	"sync"          // sync
+	"testing"       // testing
+	"time"          // time
+
+	"github.com/thorstenrie/tserr" // tserr
+	"github.com/thorstenrie/tsfio" // tsfio
+	"github.com/thorstenrie/tslog" // tslog
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu sync.Mutex   // mutex for the buffer
+	b  bytes.Buffer // buffer
+}
+
+// Write appends p to the buffer.
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	// Lock the buffer and unlock it on return
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// Append p
+	return b.b.Write(p)
+}
+
+// String returns the contents of the buffer.
+func (b *syncBuffer) String() string {
+	// Lock the buffer and unlock it on return
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// Return the contents
+	return b.b.String()
+}
+
+// TestTail runs subcommand tail on a file with 12 lines. The test fails if not exactly the
+// entries of the last lines are written.
+func TestTail(t *testing.T) {
+	// Write 12 log messages to a file
+	fn := filepath.Join(t.TempDir(), "test.log")
+	var b strings.Builder
+	for i := 1; i <= 12; i++ {
+		fmt.Fprintf(&b, `{"log":{"level":"info","message":"m%d","time":"2023-01-02 15:04:05 +0000 UTC"}}`+"\n", i)
+	}
+	if err := os.WriteFile(fn, []byte(b.String()), 0600); err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "WriteFile", Fn: fn, Err: err}))
+	}
+	// Iterate arguments and the expected first and number of entries
+	for _, c := range []struct {
+		args  []string // arguments
+		first string   // first expected entry
+		n     int      // number of expected entries
+	}{
+		{[]string{fn}, "m3", 10},
+		{[]string{"-n", "2", fn}, "m11", 2},
+		{[]string{"-n", "-1", fn}, "m1", 12},
+		{[]string{"-n", "20", "-json", fn}, "m1", 12},
+	} {
+		// Run subcommand tail
+		out, _, code := testRun(append([]string{tailCommand}, c.args...), "")
+		// Record an error, if the command fails
+		if code != 0 {
+			t.Error(tserr.Equal(&tserr.EqualArgs{Var: "exit code", Actual: int64(code), Want: 0}))
+		}
+		// Record an error, if the number of entries does not match
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if len(lines) != c.n {
+			t.Error(tserr.Equal(&tserr.EqualArgs{Var: fmt.Sprint(c.args), Actual: int64(len(lines)), Want: int64(c.n)}))
+		}
+		// Record an error, if the first entry does not match
+		if !strings.HasSuffix(lines[0], " "+c.first) && !strings.Contains(lines[0], `"`+c.first+`"`) {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: c.first, Y: lines[0]}))
+		}
+	}
+}
+
+// TestTailFollow follows a file written by a logger through truncation and rotation by
+// renaming. The test fails if an entry is missing or written twice.
+func TestTailFollow(t *testing.T) {
+	// Create logger lg logging to file fn
+	fn := filepath.Join(t.TempDir(), "test.log")
+	lg := testTailLogger(t, fn)
+	lg.Info("m1")
+	// Follow the file from its beginning
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var out, eout syncBuffer
+	done := make(chan int)
+	go func() {
+		done <- run(ctx, []string{tailCommand, "-f", "-n", "-1", "-poll", "5ms", "-level", "info", fn}, nil, &out, &eout)
+	}()
+	testTailWait(t, &out, "m1")
+	// Log a Debug entry below the filter level and an entry
+	lg.Debug("d1")
+	lg.Info("m2")
+	testTailWait(t, &out, "m2")
+	// Truncate the file, wait for the truncation to be detected and log an entry
+	if err := os.Truncate(fn, 0); err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "Truncate", Fn: fn, Err: err}))
+	}
+	time.Sleep(50 * time.Millisecond)
+	lg.Info("m3")
+	testTailWait(t, &out, "m3")
+	// Rotate the file by renaming, log an entry to the renamed file and an entry to the new file
+	if err := os.Rename(fn, fn+".1"); err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "Rename", Fn: fn, Err: err}))
+	}
+	lg.Info("m4")
+	lg.Close()
+	lg = testTailLogger(t, fn)
+	lg.Info("m5")
+	testTailWait(t, &out, "m5")
+	lg.Close()
+	// Stop following and record an error, if the command fails
+	cancel()
+	if code := <-done; code != 0 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "exit code", Actual: int64(code), Want: 0}))
+	}
+	// Retrieve the messages
+	var msgs []string
+	for _, l := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		f := strings.Fields(l)
+		msgs = append(msgs, f[len(f)-1])
+	}
+	// Record an error, if an entry is missing or written twice
+	if m := strings.Join(msgs, " "); m != "m1 m2 m3 m4 m5" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "m1 m2 m3 m4 m5", Y: m}))
+	}
+	// Record an error, if an error is reported
+	if e := eout.String(); e != "" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "", Y: e}))
+	}
+}
+
+// testTailLogger returns a new logger at Debug level logging to file fn.
+func testTailLogger(t *testing.T, fn string) *tslog.Logger {
+	// Create the logger
+	lg := tslog.New()
+	lg.SetLevel(tslog.DebugLevel)
+	// Stop execution, if setting the output fails
+	if err := lg.SetOutput(tsfio.Filename(fn)); err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "SetOutput", Fn: fn, Err: err}))
+	}
+	// Return the logger
+	return lg
+}
+
+// testTailWait waits until out contains message msg. The test stops, if msg is not written
+// within five seconds.
+func testTailWait(t *testing.T, out *syncBuffer, msg string) {
+	// Poll out until the deadline
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if strings.Contains(out.String(), " "+msg+"\n") {
+			return
+		}
+	}
+	// Stop execution, if msg is not written
+	t.Fatal(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: msg, Y: out.String()}))
+}
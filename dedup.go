@@ -0,0 +1,114 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages.
+import (
+	"fmt"  // fmt
+	"sync" // sync
+	"time" // time
+)
+
+// dedup contains the state for collapsing consecutive duplicate log messages.
+type dedup struct {
+	mu     sync.Mutex    // mutex for the deduplication state
+	window time.Duration // window for suppressing repeats, deduplication is disabled if zero
+	last   samplekey     // level, message and fields of the current run
+	active bool          // true, if a run is active
+	count  int           // number of suppressed repeats in the current run
+	until  time.Time     // end of the window of the current run
+	run    uint64        // id of the current run
+	timer  *time.Timer   // timer reporting the repeats, when the window of the current run closes
+}
+
+// SetDedup enables collapsing of consecutive duplicate log messages. Repeats of the same
+// level, message and fields within window after the first message are suppressed. When a
+// different message is logged, the window closes or the logger is closed, a single message
+// "last message repeated N times" is logged at the level of the repeated message. When the
+// window closes, the message is logged in the background.
+// If window is zero or negative, deduplication is disabled and pending repeats are reported.
+func (l *Logger) SetDedup(window time.Duration) {
+	// Lock the deduplication state
+	l.dedup.mu.Lock()
+	// Unlock the deduplication state on return
+	defer l.dedup.mu.Unlock()
+	// Report pending repeats and end the current run
+	l.endRun()
+	// Disable deduplication, if window is not positive
+	if window < 0 {
+		window = 0
+	}
+	// Set window
+	l.dedup.window = window
+}
+
+// deduplicate returns true, if entry e is logged and false, if it is suppressed as a
+// repeat of the same level, message and fields. If a run of repeats ends, the number
+// of repeats is logged.
+func (l *Logger) deduplicate(e *Entry) bool {
+	// d holds the deduplication state
+	d := &l.dedup
+	// Lock the deduplication state
+	d.mu.Lock()
+	// Unlock the deduplication state on return
+	defer d.mu.Unlock()
+	// Log message, if deduplication is disabled
+	if d.window == 0 {
+		return true
+	}
+	// Report pending repeats and end the current run, if its window closed
+	if d.active && e.Time.After(d.until) {
+		l.endRun()
+	}
+	// Suppress entry, if it repeats the entry of the current run
+	k := e.key()
+	if d.active && (d.last == k) {
+		d.count++
+		// Report the repeats, when the window closes, if e is the first repeat
+		if d.count == 1 {
+			run := d.run
+			d.timer = time.AfterFunc(time.Until(d.until), func() { l.closeRun(run) })
+		}
+		// Return false to suppress the message
+		return false
+	}
+	// Report pending repeats and end the current run
+	l.endRun()
+	// Start a new run with e, which closes after window
+	d.last, d.active, d.until = k, true, e.Time.Add(d.window)
+	d.run++
+	// Return true to log the message
+	return true
+}
+
+// closeRun reports pending repeats and ends the current run, if it has id run. It is called,
+// when the window of the run closes.
+func (l *Logger) closeRun(run uint64) {
+	// Lock the deduplication state
+	l.dedup.mu.Lock()
+	// Unlock the deduplication state on return
+	defer l.dedup.mu.Unlock()
+	// End the current run, if it was not ended meanwhile
+	if l.dedup.active && (l.dedup.run == run) {
+		l.endRun()
+	}
+}
+
+// endRun logs the number of suppressed repeats of the current run, if any, and ends the run.
+// The deduplication state must be locked.
+func (l *Logger) endRun() {
+	// d holds the deduplication state
+	d := &l.dedup
+	// Stop the timer of the current run, if any
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	// Log the number of suppressed repeats, if any
+	if d.active && (d.count > 0) {
+		l.notice(d.last.lvl, fmt.Sprintf("last message repeated %d times", d.count))
+	}
+	// End the run
+	d.active, d.count = false, 0
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"errors"  // errors
+	"sync"    // sync
+	"testing" // testing
+	"time"    // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// TestDedup logs the same error four times followed by another message twice and closes
+// the logger. The test fails if the repeats are not collapsed into a single message
+// at the end of each run.
+func TestDedup(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Enable deduplication
+	lg.SetDedup(time.Hour)
+	// Log the same error four times
+	for i := 0; i < 4; i++ {
+		if err := lg.Error(errors.New("a")); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Error", Fn: string(fn), Err: err}))
+		}
+	}
+	// Log another message twice
+	for i := 0; i < 2; i++ {
+		if err := lg.Info("b"); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: string(fn), Err: err}))
+		}
+	}
+	// Close the logger
+	if err := lg.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: string(fn), Err: err}))
+	}
+	// Evaluate the logged messages
+	testWant(t, fn, []logmsg{
+		{Lvl: "error", Msg: "a"},
+		{Lvl: "error", Msg: "last message repeated 3 times"},
+		{Lvl: "info", Msg: "b"},
+		{Lvl: "info", Msg: "last message repeated 1 times"},
+	})
+}
+
+// TestDedupWindow logs the same message three times with a short window, waits for the window
+// to close and logs the message again. The test fails if the repeats are not reported before
+// the message is logged again.
+func TestDedupWindow(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Enable deduplication with a short window
+	lg.SetDedup(10 * time.Millisecond)
+	// Log the same message three times
+	for i := 0; i < 3; i++ {
+		if err := lg.Warn("a"); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Warn", Fn: string(fn), Err: err}))
+		}
+	}
+	// Wait for the window to close and log the message again
+	time.Sleep(50 * time.Millisecond)
+	if err := lg.Warn("a"); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Warn", Fn: string(fn), Err: err}))
+	}
+	// Disable deduplication
+	lg.SetDedup(0)
+	// Evaluate the logged messages
+	testWant(t, fn, []logmsg{{Lvl: "warn", Msg: "a"}, {Lvl: "warn", Msg: "last message repeated 2 times"}, {Lvl: "warn", Msg: "a"}})
+}
+
+// TestDedupExpire logs the same message twice with a short window and waits for the window
+// to close. The test fails if the repeat is not reported without another log call.
+func TestDedupExpire(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Enable deduplication with a short window
+	lg.SetDedup(10 * time.Millisecond)
+	// Log the same message twice
+	for i := 0; i < 2; i++ {
+		if err := lg.Info("a"); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: string(fn), Err: err}))
+		}
+	}
+	// Wait for the window to close
+	time.Sleep(50 * time.Millisecond)
+	// Evaluate the logged messages before closing the logger
+	testWant(t, fn, []logmsg{{Lvl: "info", Msg: "a"}, {Lvl: "info", Msg: "last message repeated 1 times"}})
+	// Close the logger
+	if err := lg.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: string(fn), Err: err}))
+	}
+}
+
+// TestDedupSetOutput logs a repeated message with a short window and changes the format and
+// the output, while the window closes. The test fails if the repeat is not reported exactly
+// once in order. Run with -race, it fails if reporting the repeat races with SetFormat or
+// SetOutput.
+func TestDedupSetOutput(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Enable deduplication with a short window
+	lg.SetDedup(5 * time.Millisecond)
+	// Log the same message twice
+	for i := 0; i < 2; i++ {
+		if err := lg.Info("a"); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: string(fn), Err: err}))
+		}
+	}
+	// Set the format and the output to temporary file fn2, while the window closes
+	time.Sleep(5 * time.Millisecond)
+	if err := lg.SetFormat(JSONFormat); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "SetFormat", Fn: string(fn), Err: err}))
+	}
+	fn2 := tmp(t)
+	if err := lg.SetOutput(fn2); err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "SetOutput", Fn: string(fn2), Err: err}))
+	}
+	// Wait for the window to close, log another message and close the logger
+	time.Sleep(20 * time.Millisecond)
+	if err := lg.Info("b"); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: string(fn2), Err: err}))
+	}
+	if err := lg.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: string(fn2), Err: err}))
+	}
+	// Retrieve the logged messages of both outputs
+	msgs := append(testMessages(t, fn), testMessages(t, fn2)...)
+	want := []string{"a", "last message repeated 1 times", "b"}
+	// Stop execution, if the number of logged messages does not match
+	if len(msgs) != len(want) {
+		t.Fatal(tserr.Equal(&tserr.EqualArgs{Var: "No. lines", Actual: int64(len(msgs)), Want: int64(len(want))}))
+	}
+	// Record an error, if a message does not match
+	for i := range want {
+		if msgs[i].Msg != want[i] {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: want[i], Y: msgs[i].Msg}))
+		}
+	}
+}
+
+// TestClose closes a logger and logs a message afterwards. The test fails if Close
+// returns an error or if the message is logged.
+func TestClose(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Close the logger
+	if err := lg.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: string(fn), Err: err}))
+	}
+	// Log a message
+	if err := lg.Info("test"); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: string(fn), Err: err}))
+	}
+	// Evaluate the number of logged lines
+	testLines(t, fn, 0)
+}
+
+// TestSetOutputOpen sets the output of a logger to another file. The test fails if the
+// previous output file is closed.
+func TestSetOutputOpen(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Retrieve the output file and set the output to temporary file fn2
+	f, fn2 := lg.file, tmp(t)
+	if err := lg.SetOutput(fn2); err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "SetOutput", Fn: string(fn2), Err: err}))
+	}
+	// Record an error, if the previous output file is closed
+	if _, err := f.WriteString("test\n"); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "WriteString", Fn: string(fn), Err: err}))
+	}
+	// Close the previous output file and the logger
+	f.Close()
+	if err := lg.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: string(fn2), Err: err}))
+	}
+	// Evaluate the number of lines of both files
+	testLines(t, fn, 1)
+	testLines(t, fn2, 0)
+}
+
+// TestSetOutputConcurrent logs messages while the output is changed concurrently. The test
+// fails if logging returns an error or, with the race detector, if the output is not guarded.
+func TestSetOutputConcurrent(t *testing.T) {
+	// Create new logger lg discarding messages
+	lg, fn := New(), DiscardLogger
+	lg.SetOutput(fn)
+	// Close the logger on return
+	defer lg.Close()
+	// wg waits for the logging goroutine
+	var wg sync.WaitGroup
+	wg.Add(1)
+	// Log messages in a goroutine
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if err := lg.Info("test"); err != nil {
+				t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: string(fn), Err: err}))
+			}
+		}
+	}()
+	// Change the output concurrently
+	for i := 0; i < 100; i++ {
+		lg.SetOutput(fn)
+	}
+	// Wait for the logging goroutine
+	wg.Wait()
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"encoding/json" // json
+	"fmt"           // fmt
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// Field keys holding error details
+const (
+	ErrorField      string = "error"       // error or error message
+	StackTraceField string = "stack_trace" // stack trace of the error
+)
+
+// Defaults for ECS
+const (
+	// Version of the Elastic Common Schema
+	ecsVersion string = "8.11"
+	// Default namespace of the fields
+	defaultECSNamespace string = "tslog"
+	// Timestamp layout of @timestamp
+	ecsLayout string = "2006-01-02T15:04:05.000000000Z07:00"
+)
+
+// ECSArgs contains the configuration of an ECS encoder.
+type ECSArgs struct {
+	Service   string // service.name, omitted if empty
+	Namespace string // namespace of the fields, default tslog
+}
+
+// ecsmsg holds the ECS fields of a log message in the order of the ECS logging specification.
+type ecsmsg struct {
+	Time    string `json:"@timestamp"`                  // timestamp in UTC
+	Lvl     string `json:"log.level"`                   // log level
+	Msg     string `json:"message"`                     // log message
+	Err     string `json:"error.message,omitempty"`     // error message
+	Stack   string `json:"error.stack_trace,omitempty"` // stack trace
+	Service string `json:"service.name,omitempty"`      // service name
+	Version string `json:"ecs.version"`                 // ECS version
+}
+
+// NewECSEncoder returns an Encoder for the Elastic Common Schema (ECS) with configuration a.
+// If a is nil, the defaults are used. Each entry is encoded with the ECS fields @timestamp,
+// log.level, message, service.name and ecs.version. For Error and Fatal level, the message
+// of the error is also encoded as error.message. Fields ErrorField and StackTraceField
+// are encoded as error.message and error.stack_trace. The other fields are encoded
+// as object under the namespace.
+func NewECSEncoder(a *ECSArgs) Encoder {
+	// c holds a copy of the configuration
+	var c ECSArgs
+	if a != nil {
+		c = *a
+	}
+	// Set the default namespace, if empty
+	if c.Namespace == "" {
+		c.Namespace = defaultECSNamespace
+	}
+	// Encode the namespace, which cannot fail for strings
+	ns, _ := json.Marshal(c.Namespace)
+	// Return the encoder
+	return func(e *Entry) ([]byte, error) {
+		return ecsFormat(e, c.Service, ns)
+	}
+}
+
+// ecsFormat encodes entry e into an ECS log message with service name service and the fields
+// under JSON encoded namespace ns. It returns nil and an error, if the log level is invalid or
+// JSON encoding fails.
+func ecsFormat(e *Entry, service string, ns []byte) ([]byte, error) {
+	// Retrieve string representation for log level
+	ls, errl := level(e.Level)
+	// Return nil and an error for invalid log levels
+	if errl != nil {
+		return nil, errl
+	}
+	// data holds the ECS fields
+	data := ecsmsg{Time: e.Time.UTC().Format(ecsLayout), Lvl: ls, Msg: e.Message, Service: service, Version: ecsVersion}
+	// f holds the fields without the error details
+	f := make(map[string]any, len(e.Fields))
+	for k, v := range e.Fields {
+		f[k] = v
+	}
+	// Set the error message to the message for Error and Fatal level, since it is logged from an error
+	if e.Level >= ErrorLevel {
+		data.Err = e.Message
+	}
+	// Move the error details from the fields to the ECS fields
+	if v, ok := f[ErrorField]; ok {
+		data.Err = ecsString(v)
+		delete(f, ErrorField)
+	}
+	if v, ok := f[StackTraceField]; ok {
+		data.Stack = ecsString(v)
+		delete(f, StackTraceField)
+	}
+	// Retrieve the JSON encoding of data
+	j, errj := json.Marshal(&data)
+	// Return nil and an error, if JSON encoding fails
+	if errj != nil {
+		return nil, tserr.Op(&tserr.OpArgs{Op: "JSON Marshal", Fn: e.Message, Err: errj})
+	}
+	// Return the ECS fields, if there are no other fields
+	if len(f) == 0 {
+		return j, nil
+	}
+	// Retrieve the JSON encoding of the fields
+	jf, errf := json.Marshal(f)
+	// Return nil and an error, if JSON encoding fails
+	if errf != nil {
+		return nil, tserr.Op(&tserr.OpArgs{Op: "JSON Marshal", Fn: e.Message, Err: errf})
+	}
+	// Append the fields under the namespace to the ECS fields
+	j = append(j[:len(j)-1], ',')
+	j = append(append(append(j, ns...), ':'), jf...)
+	// Return the ECS log message and nil
+	return append(j, '}'), nil
+}
+
+// ecsString returns the message of v, if v is an error, or its string representation otherwise.
+func ecsString(v any) string {
+	// Return the message of an error
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	// Return the string representation otherwise
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"encoding/json" // json
+	"errors"        // errors
+	"fmt"           // fmt
+	"strings"       // strings
+	"testing"       // testing
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// TestECSEncoder logs an entry with error details and a field with an ECS encoder with
+// service name and namespace. The test fails if the ECS fields do not match.
+func TestECSEncoder(t *testing.T) {
+	// Log an entry with the ECS encoder
+	m, line := testECS(t, func(lg *Logger) {
+		lg.SetEncoder(NewECSEncoder(&ECSArgs{Service: "svc", Namespace: "app"}))
+	})
+	// Iterate expected ECS fields
+	for k, want := range map[string]any{"log.level": "error", "message": "test", "error.message": "boom", "error.stack_trace": "main.go:1", "service.name": "svc", "ecs.version": ecsVersion} {
+		// Record an error, if the ECS field does not match
+		if m[k] != want {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: fmt.Sprint(want), Y: fmt.Sprint(m[k])}))
+		}
+	}
+	// Record an error, if the field is not in the namespace
+	if ns, ok := m["app"].(map[string]any); !ok || (len(ns) != 1) || (ns["tenant"] != "a") {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: `"app":{"tenant":"a"}`, Y: line}))
+	}
+	// Record an error, if the log message does not start with the timestamp
+	if !strings.HasPrefix(line, `{"@timestamp":"`) {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: `{"@timestamp":"`, Y: line}))
+	}
+}
+
+// TestECSFormat logs an entry in the ECS format. The test fails if the field is not in the
+// default namespace or the service name is not omitted.
+func TestECSFormat(t *testing.T) {
+	// Log an entry in the ECS format
+	m, line := testECS(t, func(lg *Logger) {
+		if err := lg.SetFormat(ECSFormat); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "SetFormat", Fn: string(ECSFormat), Err: err}))
+		}
+	})
+	// Record an error, if the field is not in the default namespace
+	if _, ok := m[defaultECSNamespace].(map[string]any); !ok {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: defaultECSNamespace, Y: line}))
+	}
+	// Record an error, if the service name is not omitted
+	if _, ok := m["service.name"]; ok {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "no service.name", Y: line}))
+	}
+}
+
+// testECS creates a logger logging to a temporary file configured by set and logs an entry with
+// message "test", error details and a field at Error level. It returns the decoded and raw log message.
+func testECS(t *testing.T, set func(*Logger)) (map[string]any, string) {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Create new logger lg logging to the temporary file fn
+	lg, fn := traceLogger(t)
+	// Configure the encoder
+	set(lg)
+	// Add processor setting error details and a field
+	lg.AddProcessor(func(e *Entry) bool {
+		e.SetField(ErrorField, errors.New("boom"))
+		e.SetField(StackTraceField, "main.go:1")
+		e.SetField("tenant", "a")
+		return true
+	})
+	// Log an entry at Error level
+	if err := lg.Error(errors.New("test")); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Error", Fn: string(fn), Err: err}))
+	}
+	// Retrieve the log message
+	fs := scanner(t, fn)
+	rm(t, fn)
+	if !fs.Scan() {
+		t.Fatal(tserr.NilFailed("Scan"))
+	}
+	// Decode the log message
+	var m map[string]any
+	if err := json.Unmarshal(fs.Bytes(), &m); err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "JSON Unmarshal", Fn: fs.Text(), Err: err}))
+	}
+	// Return the decoded and raw log message
+	return m, fs.Text()
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"encoding/json" // json
+	"fmt"           // fmt
+	"time"          // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// Entry is a log entry. It is created after the level check and passed to the
+// processors of the logger before it is encoded.
+type Entry struct {
+	Level   int            // log level
+	Message string         // log message
+	Time    time.Time      // timestamp
+	Fields  map[string]any // structured fields, nil if there are none
+}
+
+// Processor processes log entry e. It may change e, e.g. add or remove fields. It
+// returns true, if e is logged and false, if e is dropped.
+type Processor func(e *Entry) bool
+
+// Encoder encodes log entry e into a log message. It returns the encoded log message
+// or an error, if encoding fails.
+type Encoder func(e *Entry) ([]byte, error)
+
+// AddProcessor appends processor p to the processors of the logger. The processors are
+// executed in the order they are added for each entry with a level equal to or higher
+// than the minimum level. If a processor returns false, the entry is dropped and the
+// remaining processors are not executed. If a processor panics, the panic is recovered,
+// the remaining processors are executed and logging the entry returns an error.
+func (l *Logger) AddProcessor(p Processor) {
+	// Append p, if it is not nil
+	if p != nil {
+		l.out.Lock()
+		l.processors = append(l.processors, p)
+		l.out.Unlock()
+	}
+}
+
+// SetField sets field key to value v. It allocates the fields, if needed.
+func (e *Entry) SetField(key string, v any) {
+	// Allocate fields, if needed
+	if e.Fields == nil {
+		e.Fields = make(map[string]any)
+	}
+	// Set field key to v
+	e.Fields[key] = v
+}
+
+// clone returns a copy of e with a copy of its fields.
+func (e *Entry) clone() Entry {
+	// Copy e
+	c := *e
+	// Copy the fields, if any
+	if e.Fields != nil {
+		c.Fields = make(map[string]any, len(e.Fields))
+		for k, v := range e.Fields {
+			c.Fields[k] = v
+		}
+	}
+	// Return the copy
+	return c
+}
+
+// process executes all processors of the logger on e. It returns true, if e is logged and
+// false, if e is dropped. It returns an error, if a processor panics.
+func (l *Logger) process(e *Entry) (bool, error) {
+	// err holds the error of the first panicking processor, if any
+	var err error
+	// Iterate all processors in the order they are added
+	for i, p := range l.processors {
+		// Execute processor p
+		ok, perr := runProcessor(p, e)
+		// Keep the error of the first panicking processor
+		if (perr != nil) && (err == nil) {
+			err = tserr.Op(&tserr.OpArgs{Op: fmt.Sprintf("processor %d on", i), Fn: e.Message, Err: perr})
+		}
+		// Return false, if the processor drops e
+		if !ok {
+			return false, err
+		}
+	}
+	// Return true to log e
+	return true, err
+}
+
+// runProcessor executes processor p on e. It recovers a panic of p and returns true
+// and an error in that case.
+func runProcessor(p Processor, e *Entry) (ok bool, err error) {
+	// Recover a panic of p
+	defer func() {
+		if r := recover(); r != nil {
+			ok, err = true, fmt.Errorf("panic: %v", r)
+		}
+	}()
+	// Execute p
+	return p(e), nil
+}
+
+// key returns the level, message and fields of e to identify duplicates.
+func (e *Entry) key() samplekey {
+	// Return level and message, if e has no fields
+	if len(e.Fields) == 0 {
+		return samplekey{lvl: e.Level, msg: e.Message}
+	}
+	// Encode the fields with sorted keys
+	f, err := json.Marshal(e.Fields)
+	// Fall back to the default format, if encoding fails
+	if err != nil {
+		f = []byte(fmt.Sprint(e.Fields))
+	}
+	// Return level, message and fields
+	return samplekey{lvl: e.Level, msg: e.Message, fields: string(f)}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"fmt"     // fmt
+	"testing" // testing
+	"time"    // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// TestProcessor adds a processor setting a field, a processor dropping health messages
+// and a panicking processor. The test fails if the field is not logged, if the health
+// message is logged or if the panic is not returned as an error.
+func TestProcessor(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Add processor setting field host
+	lg.AddProcessor(func(e *Entry) bool {
+		e.SetField("host", "test")
+		return true
+	})
+	// Add panicking processor
+	lg.AddProcessor(func(e *Entry) bool {
+		if e.Message == "panic" {
+			panic("test")
+		}
+		return true
+	})
+	// Add processor dropping health messages
+	lg.AddProcessor(func(e *Entry) bool {
+		return e.Message != "health"
+	})
+	// Log a message and a health message
+	for _, msg := range []string{"test", "health"} {
+		if err := lg.Info(msg); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: string(fn), Err: err}))
+		}
+	}
+	// Record an error, if the panic is not returned
+	if err := lg.Info("panic"); err == nil {
+		t.Error(tserr.NilFailed("Info with panicking processor"))
+	}
+	// Retrieve the logged messages
+	msgs := testMessages(t, fn)
+	// Stop execution, if the number of logged messages is not two
+	if len(msgs) != 2 {
+		t.Fatal(tserr.Equal(&tserr.EqualArgs{Var: "No. lines", Actual: int64(len(msgs)), Want: 2}))
+	}
+	// Record an error, if field host is not logged
+	for _, m := range msgs {
+		if h := fmt.Sprint(m.Fields["host"]); h != "test" {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "test", Y: h}))
+		}
+	}
+}
+
+// TestDedupFields logs the same message three times with a processor setting a different
+// field for each entry. The test fails if the entries are collapsed.
+func TestDedupFields(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Enable deduplication
+	lg.SetDedup(time.Hour)
+	// Add processor setting a counter as field
+	n := 0
+	lg.AddProcessor(func(e *Entry) bool {
+		n++
+		e.SetField("n", n)
+		return true
+	})
+	// Log the same message three times
+	for i := 0; i < 3; i++ {
+		if err := lg.Info("test"); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: string(fn), Err: err}))
+		}
+	}
+	// Evaluate the number of logged lines
+	testLines(t, fn, 3)
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tsfio.
+import (
+	"flag"    // flag
+	"fmt"     // fmt
+	"strconv" // strconv
+
+	"github.com/thorstenrie/tsfio" // tsfio
+)
+
+// Names of the flags registered by RegisterFlags
+const (
+	LevelFlag   string = "log-level"   // minimum log level
+	OutputFlag  string = "log-output"  // logging output
+	FormatFlag  string = "log-format"  // format of the log messages
+	VFlag       string = "log-v"       // verbosity for V
+	VModuleFlag string = "log-vmodule" // per-file verbosity overrides for V
+)
+
+// levelFlag implements flag.Value for the minimum log level of a logger.
+type levelFlag struct {
+	l *Logger // logger
+}
+
+// outputFlag implements flag.Value for the logging output of a logger.
+type outputFlag struct {
+	l  *Logger        // logger
+	fn tsfio.Filename // last set logging output
+}
+
+// formatFlag implements flag.Value for the format of a logger.
+type formatFlag struct {
+	l *Logger // logger
+}
+
+// vFlag implements flag.Value for the verbosity of a logger.
+type vFlag struct {
+	l *Logger // logger
+}
+
+// vmoduleFlag implements flag.Value for the per-file verbosity overrides of a logger.
+type vmoduleFlag struct {
+	l    *Logger // logger
+	spec string  // last set vmodule specification
+}
+
+// RegisterFlags registers the flags log-level, log-output, log-format, log-v and
+// log-vmodule on fs. The flags are bound to the global predefined standard logger. Setting
+// a flag calls SetLevel, SetOutput, SetFormat, SetVerbosity or SetVModule respectively.
+func RegisterFlags(fs *flag.FlagSet) {
+	globalLogger.RegisterFlags(fs)
+}
+
+// RegisterFlags registers the flags log-level, log-output, log-format, log-v and
+// log-vmodule on fs. The flags are bound to logger l. Setting a flag calls
+// SetLevel, SetOutput, SetFormat, SetVerbosity or SetVModule respectively.
+// It panics, if fs is nil.
+func (l *Logger) RegisterFlags(fs *flag.FlagSet) {
+	// Panic if fs is nil
+	if fs == nil {
+		panic("nil pointer")
+	}
+	// Register flag for the minimum log level
+	fs.Var(&levelFlag{l: l}, LevelFlag, fmt.Sprintf("minimum log level, one of %s or %d to %d", levelNames(), TraceLevel, FatalLevel))
+	// Register flag for the logging output
+	fs.Var(&outputFlag{l: l, fn: StdoutLogger}, OutputFlag, fmt.Sprintf("logging output, one of %s, %s, %s or a filename", StdoutLogger, TmpLogger, DiscardLogger))
+	// Register flag for the format
+	fs.Var(&formatFlag{l: l}, FormatFlag, fmt.Sprintf("format of the log messages, one of %s", formatNames()))
+	// Register flag for the verbosity
+	fs.Var(&vFlag{l: l}, VFlag, "verbosity for V below trace level")
+	// Register flag for the vmodule specification
+	fs.Var(&vmoduleFlag{l: l}, VModuleFlag, "comma separated list of pattern=N for per-file verbosity overrides")
+}
+
+// String returns the string representation of the minimum log level.
+func (f *levelFlag) String() string {
+	// Return an empty string for the zero value
+	if (f == nil) || (f.l == nil) {
+		return ""
+	}
+	// Retrieve string representation of the minimum log level
+	ls, _ := level(f.l.minLvl)
+	// Return the string representation
+	return ls
+}
+
+// Set parses s and sets the minimum log level. It returns an error, if s is not a defined log level.
+func (f *levelFlag) Set(s string) error {
+	// Parse the log level
+	lvl, err := ParseLevel(s)
+	// Return an error, if parsing fails
+	if err != nil {
+		return err
+	}
+	// Set the minimum log level
+	return f.l.SetLevel(lvl)
+}
+
+// String returns the last set logging output.
+func (f *outputFlag) String() string {
+	// Return an empty string for the zero value
+	if f == nil {
+		return ""
+	}
+	// Return the last set logging output
+	return string(f.fn)
+}
+
+// Set sets the logging output to s. It returns an error, if SetOutput fails.
+func (f *outputFlag) Set(s string) error {
+	// Set the logging output
+	if err := f.l.SetOutput(tsfio.Filename(s)); err != nil {
+		// Remember fallback to Stdout
+		f.fn = StdoutLogger
+		// Return error
+		return err
+	}
+	// Remember the logging output
+	f.fn = tsfio.Filename(s)
+	// Return nil
+	return nil
+}
+
+// String returns the format of the logger.
+func (f *formatFlag) String() string {
+	// Return an empty string for the zero value
+	if (f == nil) || (f.l == nil) {
+		return ""
+	}
+	// Return the format
+	return string(f.l.format)
+}
+
+// Set sets the format to s. It returns an error, if s is not a defined format.
+func (f *formatFlag) Set(s string) error {
+	return f.l.SetFormat(Format(s))
+}
+
+// String returns the verbosity of the logger.
+func (f *vFlag) String() string {
+	// Return an empty string for the zero value
+	if (f == nil) || (f.l == nil) {
+		return ""
+	}
+	// Return the verbosity
+	return strconv.Itoa(int(f.l.verbosity.Load()))
+}
+
+// Set parses s and sets the verbosity. It returns an error, if s is not a non-negative number.
+func (f *vFlag) Set(s string) error {
+	// Parse the verbosity
+	v, err := strconv.Atoi(s)
+	// Return an error, if parsing fails
+	if err != nil {
+		return err
+	}
+	// Set the verbosity
+	return f.l.SetVerbosity(v)
+}
+
+// String returns the last set vmodule specification.
+func (f *vmoduleFlag) String() string {
+	// Return an empty string for the zero value
+	if f == nil {
+		return ""
+	}
+	// Return the last set vmodule specification
+	return f.spec
+}
+
+// Set sets the vmodule specification to s. It returns an error, if s is malformed.
+func (f *vmoduleFlag) Set(s string) error {
+	// Set the vmodule specification
+	if err := f.l.SetVModule(s); err != nil {
+		return err
+	}
+	// Remember the vmodule specification
+	f.spec = s
+	// Return nil
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"flag"    // flag
+	"fmt"     // fmt
+	"io"      // io
+	"strings" // strings
+	"testing" // testing
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// TestFlags registers the flags on a new flag set bound to a new logger and parses
+// valid arguments for all flags. The test fails if parsing fails or if the logger
+// is not configured according to the arguments.
+func TestFlags(t *testing.T) {
+	// Create the temporary file fn
+	fn := tmp(t)
+	// Create new logger lg
+	lg := New()
+	// Create new flag set fs
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	// Register flags on fs bound to lg
+	lg.RegisterFlags(fs)
+	// Arguments for all flags
+	args := []string{"-" + LevelFlag + "=trace", "-" + OutputFlag + "=" + string(fn), "-" + FormatFlag + "=json", "-" + VFlag + "=2", "-" + VModuleFlag + "=other=3"}
+	// Parse arguments
+	if err := fs.Parse(args); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Parse", Fn: strings.Join(args, " "), Err: err}))
+	}
+	// Record an error if the minimum level is not Trace level
+	if lg.minLvl != TraceLevel {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "minimum level", Actual: int64(lg.minLvl), Want: int64(TraceLevel)}))
+	}
+	// Record an error if the verbosity is not two
+	if v := lg.verbosity.Load(); v != 2 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "verbosity", Actual: int64(v), Want: 2}))
+	}
+	// Record an error if the flag values are not returned
+	for f, want := range map[string]string{LevelFlag: "trace", OutputFlag: string(fn), FormatFlag: "json", VFlag: "2", VModuleFlag: "other=3"} {
+		if got := fs.Lookup(f).Value.String(); got != want {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: want, Y: got}))
+		}
+	}
+	// Log at verbosity level two
+	if err := lg.V(2).Log("test"); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Log verbose", Fn: string(fn), Err: err}))
+	}
+	// Evaluate the number of logged lines
+	testLines(t, fn, 1)
+}
+
+// TestFlagsErr parses invalid arguments for each flag. The test fails if parsing
+// does not return an error.
+func TestFlagsErr(t *testing.T) {
+	// Create the temporary directory d
+	d := tmpDir(t)
+	// Iterate invalid arguments
+	for _, arg := range []string{LevelFlag + "=verbose", LevelFlag + "=0", OutputFlag + "=" + string(d), FormatFlag + "=xml", VFlag + "=x", VFlag + "=-1", VModuleFlag + "=x"} {
+		// Create new flag set fs bound to a new logger
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		// Discard usage output
+		fs.SetOutput(io.Discard)
+		// Register flags on fs
+		New().RegisterFlags(fs)
+		// Record an error if Parse returns nil
+		if err := fs.Parse([]string{"-" + arg}); err == nil {
+			t.Error(tserr.NilFailed("Parse " + arg))
+		}
+	}
+	// Remove the temporary directory d
+	rm(t, d)
+}
+
+// TestFlagsUsage registers the flags on a new flag set bound to the global predefined standard
+// logger. The test fails if the help text of the level and format flags does not list the valid values.
+func TestFlagsUsage(t *testing.T) {
+	// Create new flag set fs
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	// Register flags on fs
+	RegisterFlags(fs)
+	// Iterate flags and their expected valid values
+	for f, want := range map[string]string{LevelFlag: "trace, debug, info, warn, error, fatal", FormatFlag: string(JSONFormat)} {
+		// Record an error if the help text does not contain the valid values
+		if u := fs.Lookup(f).Usage; !strings.Contains(u, want) {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: want, Y: u}))
+		}
+	}
+}
+
+// TestParseLevel parses the string representation and the number of all log levels.
+// The test fails if a log level is not parsed or if an undefined level is parsed.
+func TestParseLevel(t *testing.T) {
+	// Iterate all log levels
+	for lvl := TraceLevel; lvl <= FatalLevel; lvl++ {
+		// Retrieve string representation of lvl
+		ls, _ := level(lvl)
+		// Parse the upper case string representation and the number of lvl
+		for _, s := range []string{strings.ToUpper(ls), fmt.Sprint(lvl)} {
+			// Record an error if parsing fails or returns a different log level
+			if p, err := ParseLevel(s); (err != nil) || (p != lvl) {
+				t.Error(tserr.Op(&tserr.OpArgs{Op: "Parse level", Fn: s, Err: err}))
+			}
+		}
+	}
+	// Record an error if an undefined level is parsed
+	if _, err := ParseLevel("7"); err == nil {
+		t.Error(tserr.NilFailed("Parse level 7"))
+	}
+}
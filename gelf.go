@@ -0,0 +1,306 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"bytes"         // bytes
+	"compress/gzip" // gzip
+	"crypto/rand"   // rand
+	"encoding/json" // json
+	"fmt"           // fmt
+	"net"           // net
+	"os"            // os
+	"strings"       // strings
+	"sync"          // sync
+	"time"          // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// Defaults for GELF
+const (
+	// GELF version
+	gelfVersion string = "1.1"
+	// Default maximum size of a UDP datagram
+	defaultGELFChunk int = 1420
+	// Minimum size of a UDP datagram
+	gelfMinChunk int = 64
+	// Maximum number of chunks of a message
+	gelfMaxChunks int = 128
+	// Size of the chunk header with magic bytes, message id, sequence number and count
+	gelfHeader int = 12
+	// Timeout for connecting to the GELF server
+	gelfTimeout time.Duration = 5 * time.Second
+)
+
+// gelfMagic holds the magic bytes of a chunked GELF message.
+var gelfMagic = [2]byte{0x1e, 0x0f}
+
+// gelfHost holds the hostname for GELF messages.
+var gelfHost = func() string {
+	// Return the hostname, if available
+	if h, err := os.Hostname(); (err == nil) && (h != "") {
+		return h
+	}
+	// Return localhost otherwise
+	return "localhost"
+}()
+
+// gelfFormat encodes entry e into a GELF 1.1 message. The log level is mapped to its
+// syslog severity in level and its string representation in _tslog_level. Fields are
+// encoded as additional fields with prefix _. Characters other than letters, digits,
+// underscore, dash and dot in field names are replaced by an underscore and field id is
+// encoded as __id. It returns nil and an error, if the log level is invalid or JSON
+// encoding fails.
+func gelfFormat(e *Entry) ([]byte, error) {
+	// Retrieve string representation for log level
+	ls, errl := level(e.Level)
+	// Return nil and an error for invalid log levels
+	if errl != nil {
+		return nil, errl
+	}
+	// m holds the GELF message
+	m := make(map[string]any, len(e.Fields)+7)
+	// Set the fields of the GELF message
+	m["version"] = gelfVersion
+	m["host"] = gelfHost
+	m["short_message"] = e.Message
+	m["timestamp"] = float64(e.Time.UnixMicro()) / 1e6
+	m["level"] = severity(e.Level)
+	m["_tslog_level"] = ls
+	// Set the additional fields
+	for k, v := range e.Fields {
+		m["_"+gelfName(k)] = gelfValue(v)
+	}
+	// Retrieve the JSON encoding of m
+	j, errj := json.Marshal(m)
+	// Return nil and an error, if JSON encoding fails
+	if errj != nil {
+		return nil, tserr.Op(&tserr.OpArgs{Op: "JSON Marshal", Fn: e.Message, Err: errj})
+	}
+	// Return the GELF message and nil
+	return j, nil
+}
+
+// gelfName returns the additional field name for field key k without prefix.
+func gelfName(k string) string {
+	// Replace characters other than letters, digits, underscore, dash and dot by an underscore
+	n := strings.Map(func(r rune) rune {
+		if ((r >= 'a') && (r <= 'z')) || ((r >= 'A') && (r <= 'Z')) || ((r >= '0') && (r <= '9')) || (r == '_') || (r == '-') || (r == '.') {
+			return r
+		}
+		return '_'
+	}, k)
+	// Return _id for the reserved field id
+	if n == "id" {
+		return "_id"
+	}
+	// Return the name
+	return n
+}
+
+// gelfValue returns v, if v is a number, or its string representation otherwise.
+func gelfValue(v any) any {
+	// Return v for numbers
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+		return v
+	}
+	// Return the string representation otherwise
+	return fmt.Sprint(v)
+}
+
+// GELFArgs contains the configuration of a GELFSink.
+type GELFArgs struct {
+	Network  string // "udp" or "tcp"
+	Address  string // address of the GELF server
+	Compress bool   // compress messages with gzip, only for UDP
+	Chunk    int    // maximum size of a UDP datagram, default 1420
+}
+
+// GELFSink is a Sink writing entries as GELF 1.1 messages to Graylog. With UDP, messages
+// are optionally compressed with gzip and split into chunks, if they exceed the maximum
+// datagram size. With TCP, messages are terminated by a null byte. If writing fails, the
+// connection is re-established once.
+type GELFSink struct {
+	mu   sync.Mutex // mutex for the connection
+	args GELFArgs   // configuration
+	conn net.Conn   // connection to the GELF server
+}
+
+// NewGELFSink creates a new GELFSink with configuration a and connects to the GELF server.
+// It returns an error, if a is nil, the network is not supported, compression is set
+// for TCP, the chunk size is too small or connecting fails.
+func NewGELFSink(a *GELFArgs) (*GELFSink, error) {
+	// Return an error, if a is nil
+	if a == nil {
+		return nil, tserr.NilPtr()
+	}
+	// Return an error, if the network is not supported or compression is set for TCP
+	switch a.Network {
+	case "udp", "udp4", "udp6":
+	case "tcp", "tcp4", "tcp6":
+		if a.Compress {
+			return nil, tserr.NotExistent("GELF compression over TCP")
+		}
+	default:
+		return nil, tserr.NotExistent(fmt.Sprintf("network %s", a.Network))
+	}
+	// Create the sink with a copy of a
+	s := &GELFSink{args: *a}
+	// Set the default chunk size, if zero
+	if s.args.Chunk == 0 {
+		s.args.Chunk = defaultGELFChunk
+	}
+	// Return an error, if the chunk size is too small
+	if s.args.Chunk < gelfMinChunk {
+		return nil, tserr.NotExistent(fmt.Sprintf("GELF chunk size %d", s.args.Chunk))
+	}
+	// Connect to the GELF server
+	if err := s.dial(); err != nil {
+		return nil, err
+	}
+	// Return the sink
+	return s, nil
+}
+
+// Log writes entry e as GELF message. It returns an error, if encoding fails, the message
+// exceeds the maximum number of chunks or writing fails after re-establishing the connection.
+func (s *GELFSink) Log(e *Entry, _ []byte) error {
+	// Encode the GELF message
+	j, err := gelfFormat(e)
+	if err != nil {
+		return err
+	}
+	// Split the message into datagrams or frame it
+	msgs, err := s.frame(j)
+	if err != nil {
+		return err
+	}
+	// Lock the connection
+	s.mu.Lock()
+	// Unlock the connection on return
+	defer s.mu.Unlock()
+	// Write the message, if connected
+	if s.conn != nil {
+		if err := s.write(msgs); err == nil {
+			return nil
+		}
+		// Close the failed connection
+		s.conn.Close()
+		s.conn = nil
+	}
+	// Re-establish the connection
+	if err := s.dial(); err != nil {
+		return err
+	}
+	// Write the message
+	if err := s.write(msgs); err != nil {
+		return tserr.Op(&tserr.OpArgs{Op: "write to GELF server", Fn: s.args.Address, Err: err})
+	}
+	// Return nil
+	return nil
+}
+
+// Close closes the connection to the GELF server.
+func (s *GELFSink) Close() error {
+	// Lock the connection
+	s.mu.Lock()
+	// Unlock the connection on return
+	defer s.mu.Unlock()
+	// Return nil, if not connected
+	if s.conn == nil {
+		return nil
+	}
+	// Close the connection
+	err := s.conn.Close()
+	s.conn = nil
+	// Return an error, if closing fails
+	if err != nil {
+		return tserr.Op(&tserr.OpArgs{Op: "close GELF connection", Fn: s.args.Address, Err: err})
+	}
+	// Return nil
+	return nil
+}
+
+// dial connects to the GELF server. It returns an error, if connecting fails.
+func (s *GELFSink) dial() error {
+	// Connect to the address
+	c, err := net.DialTimeout(s.args.Network, s.args.Address, gelfTimeout)
+	// Return an error, if connecting fails
+	if err != nil {
+		return tserr.Op(&tserr.OpArgs{Op: "connect to GELF server", Fn: s.args.Address, Err: err})
+	}
+	// Keep the connection
+	s.conn = c
+	// Return nil
+	return nil
+}
+
+// write writes msgs to the connection. It returns an error, if writing fails.
+func (s *GELFSink) write(msgs [][]byte) error {
+	// Write each message
+	for _, m := range msgs {
+		if _, err := s.conn.Write(m); err != nil {
+			return err
+		}
+	}
+	// Return nil
+	return nil
+}
+
+// frame returns GELF message j as messages to be written. With TCP, j is terminated by
+// a null byte. With UDP, j is optionally compressed and split into chunks, if it exceeds
+// the maximum datagram size. It returns an error, if compression fails or j exceeds
+// the maximum number of chunks.
+func (s *GELFSink) frame(j []byte) ([][]byte, error) {
+	// Terminate j by a null byte for TCP
+	if strings.HasPrefix(s.args.Network, "tcp") {
+		return [][]byte{append(j, 0)}, nil
+	}
+	// Compress j, if requested
+	if s.args.Compress {
+		var b bytes.Buffer
+		w := gzip.NewWriter(&b)
+		w.Write(j)
+		if err := w.Close(); err != nil {
+			return nil, tserr.Op(&tserr.OpArgs{Op: "gzip", Fn: "GELF message", Err: err})
+		}
+		j = b.Bytes()
+	}
+	// Return j, if it does not exceed the maximum datagram size
+	if len(j) <= s.args.Chunk {
+		return [][]byte{j}, nil
+	}
+	// Retrieve the number of chunks
+	size := s.args.Chunk - gelfHeader
+	n := (len(j) + size - 1) / size
+	// Return an error, if j exceeds the maximum number of chunks
+	if n > gelfMaxChunks {
+		return nil, tserr.NotExistent(fmt.Sprintf("GELF message of %d chunks", n))
+	}
+	// Generate a random message id
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, tserr.Op(&tserr.OpArgs{Op: "generate", Fn: "GELF message id", Err: err})
+	}
+	// Split j into chunks with header
+	msgs := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		// Retrieve the data of the chunk
+		d := j[i*size:]
+		if len(d) > size {
+			d = d[:size]
+		}
+		// Append the chunk with magic bytes, message id, sequence number and count
+		c := make([]byte, 0, gelfHeader+len(d))
+		c = append(c, gelfMagic[:]...)
+		c = append(c, id[:]...)
+		c = append(c, byte(i), byte(n))
+		msgs = append(msgs, append(c, d...))
+	}
+	// Return the chunks
+	return msgs, nil
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"bytes"         // bytes
+	"compress/gzip" // gzip
+	"encoding/json" // json
+	"fmt"           // fmt
+	"io"            // io
+	"net"           // net
+	"strings"       // strings
+	"testing"       // testing
+	"time"          // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// TestGELFUDP logs a large entry with fields to a local UDP socket with compression and
+// the minimum chunk size. The test fails if the reassembled message does not contain
+// the expected GELF fields.
+func TestGELFUDP(t *testing.T) {
+	// Listen on a local UDP socket
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	// Stop execution, if listening fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "listen", Fn: "udp", Err: err}))
+	}
+	// Close the socket on return
+	defer c.Close()
+	// Create a large message, which exceeds a chunk after compression
+	n := make([]string, 500)
+	for i := range n {
+		n[i] = fmt.Sprint(i * 7919 % 10007)
+	}
+	msg := strings.Join(n, " ")
+	// Log the message at Warn level with compression and the minimum chunk size
+	testGELF(t, &GELFArgs{Network: "udp", Address: c.LocalAddr().String(), Compress: true, Chunk: gelfMinChunk}, WarnLevel, msg)
+	// Reassemble the chunks
+	b := testGELFChunks(t, c)
+	// Decompress the message
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "gzip", Fn: "GELF message", Err: err}))
+	}
+	j, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "gunzip", Fn: "GELF message", Err: err}))
+	}
+	// Evaluate the message
+	testGELFEval(t, j, map[string]any{"version": "1.1", "short_message": msg, "level": float64(4), "_tslog_level": "warn", "_tenant_id": "a", "__id": float64(1)})
+}
+
+// TestGELFTCP logs an entry to a local TCP socket. The test fails if the message is not
+// terminated by a null byte or does not contain the expected GELF fields.
+func TestGELFTCP(t *testing.T) {
+	// Listen on a local TCP socket
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	// Stop execution, if listening fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "listen", Fn: "tcp", Err: err}))
+	}
+	// Close the socket on return
+	defer ln.Close()
+	// Log an entry at Error level
+	testGELF(t, &GELFArgs{Network: "tcp", Address: ln.Addr().String()}, ErrorLevel, "test")
+	// Accept the connection of the sink
+	c, err := ln.Accept()
+	// Stop execution, if accepting fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "accept", Fn: "tcp", Err: err}))
+	}
+	// Close the connection on return
+	defer c.Close()
+	// Read the framed message
+	b, err := io.ReadAll(c)
+	// Stop execution, if reading fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "read", Fn: "tcp", Err: err}))
+	}
+	// Stop execution, if the message is not terminated by a null byte
+	if !bytes.HasSuffix(b, []byte{0}) {
+		t.Fatal(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "null byte", Y: string(b)}))
+	}
+	// Evaluate the message
+	testGELFEval(t, b[:len(b)-1], map[string]any{"short_message": "test", "level": float64(3), "_tslog_level": "error"})
+}
+
+// TestGELFFormat sets the GELF format and encodes an entry with an invalid level. The
+// test fails if the GELF format is not available or encoding does not return an error.
+func TestGELFFormat(t *testing.T) {
+	// Record an error, if setting the GELF format fails
+	if err := New().SetFormat(GELFFormat); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "SetFormat", Fn: string(GELFFormat), Err: err}))
+	}
+	// Record an error, if encoding an entry with an invalid level returns nil
+	if _, err := gelfFormat(&Entry{Level: 0, Message: "test"}); err == nil {
+		t.Error(tserr.NilFailed("gelfFormat"))
+	}
+}
+
+// TestGELFErr creates GELF sinks with invalid configurations. The test fails
+// if NewGELFSink does not return an error.
+func TestGELFErr(t *testing.T) {
+	// Iterate invalid configurations
+	for _, a := range []*GELFArgs{nil, {Network: "ip"}, {Network: "tcp", Compress: true}, {Network: "udp", Chunk: 10}} {
+		// Record an error, if NewGELFSink returns nil
+		if _, err := NewGELFSink(a); err == nil {
+			t.Error(tserr.NilFailed("New GELF sink"))
+		}
+	}
+}
+
+// testGELF creates a GELF sink with configuration a and logs an entry with message msg
+// and fields at level lvl. It closes the logger afterwards.
+func testGELF(t *testing.T, a *GELFArgs, lvl int, msg string) {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Create the GELF sink s
+	s, err := NewGELFSink(a)
+	// Stop execution, if creating the sink fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New GELF sink", Fn: a.Address, Err: err}))
+	}
+	// Create new logger lg with sink s
+	lg := New()
+	if err := lg.SetSink(s); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set sink", Fn: a.Address, Err: err}))
+	}
+	// Add processor setting fields with names to be converted
+	lg.AddProcessor(func(e *Entry) bool {
+		e.SetField("tenant id", "a")
+		e.SetField("id", 1)
+		return true
+	})
+	// Log the entry
+	if err := testLogger(&testcase{level: lvl, in: msg}, lg); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Log", Fn: a.Address, Err: err}))
+	}
+	// Close the logger
+	if err := lg.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: a.Address, Err: err}))
+	}
+}
+
+// testGELFChunks receives chunks of a GELF message on c and returns the reassembled
+// message. Execution stops, if a chunk is malformed or not received within one second.
+func testGELFChunks(t *testing.T, c net.PacketConn) []byte {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// chunks holds the received chunks indexed by sequence number
+	var chunks [][]byte
+	// Receive chunks until all chunks are received
+	for received := 0; (chunks == nil) || (received < len(chunks)); received++ {
+		// Set a read deadline of one second
+		c.SetReadDeadline(time.Now().Add(time.Second))
+		// Read the datagram
+		b := make([]byte, 65536)
+		n, _, err := c.ReadFrom(b)
+		// Stop execution, if reading fails
+		if err != nil {
+			t.Fatal(tserr.Op(&tserr.OpArgs{Op: "read", Fn: c.LocalAddr().String(), Err: err}))
+		}
+		// Stop execution, if the datagram is not a chunk
+		if (n < gelfHeader) || !bytes.HasPrefix(b, gelfMagic[:]) {
+			t.Fatal(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "GELF chunk", Y: string(b[:n])}))
+		}
+		// Retrieve the sequence number and count
+		seq, cnt := int(b[10]), int(b[11])
+		if chunks == nil {
+			chunks = make([][]byte, cnt)
+		}
+		// Keep the data of the chunk
+		chunks[seq] = b[gelfHeader:n]
+	}
+	// Return the reassembled message
+	return bytes.Join(chunks, nil)
+}
+
+// testGELFEval decodes GELF message j and records an error, if a field in want does
+// not match.
+func testGELFEval(t *testing.T, j []byte, want map[string]any) {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Decode the message
+	var m map[string]any
+	if err := json.Unmarshal(j, &m); err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "JSON Unmarshal", Fn: string(j), Err: err}))
+	}
+	// Iterate expected fields
+	for k, v := range want {
+		// Record an error, if the field does not match
+		if m[k] != v {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: fmt.Sprint(v), Y: fmt.Sprint(m[k])}))
+		}
+	}
+}
@@ -5,4 +5,5 @@ go 1.19
 require (
 	github.com/thorstenrie/tserr v1.5.1
 	github.com/thorstenrie/tsfio v1.1.1
+	golang.org/x/sys v0.10.0
 )
@@ -0,0 +1,426 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages, tserr and tsfio.
+import (
+	"bytes"       // bytes
+	"fmt"         // fmt
+	"io"          // io
+	"net/http"    // http
+	"net/url"     // url
+	"sync"        // sync
+	"sync/atomic" // atomic
+	"time"        // time
+
+	"github.com/thorstenrie/tserr" // tserr
+	"github.com/thorstenrie/tsfio" // tsfio
+)
+
+// Defaults for HTTP sinks
+const (
+	defaultHTTPBatch    int           = 100                    // entries per batch
+	defaultHTTPInterval time.Duration = time.Second            // flush interval
+	defaultHTTPBuffer   int           = 10000                  // buffered entries
+	defaultHTTPRetries  int           = 3                      // retries of a batch
+	defaultHTTPBackoff  time.Duration = 100 * time.Millisecond // initial backoff
+	defaultHTTPTimeout  time.Duration = 5 * time.Second        // request timeout
+	defaultHTTPSpool    int64         = 64 << 20               // spool size in bytes
+)
+
+// Content type of NDJSON
+const ndjson string = "application/x-ndjson"
+
+// HTTPArgs contains the configuration of an HTTPSink. Zero values are replaced by defaults.
+// Retries and backoff are disabled with NoRetry and NoBackoff.
+type HTTPArgs struct {
+	URL       string          // URL the batches are posted to
+	BatchSize int             // maximum number of entries per batch, default 100
+	Interval  time.Duration   // interval for flushing a non-empty batch, default 1s
+	Buffer    int             // maximum number of buffered entries, default 10000
+	Retries   int             // maximum number of retries of a batch, default 3, none with NoRetry
+	Backoff   time.Duration   // initial backoff, doubled for each retry, default 100ms, none with NoBackoff
+	Timeout   time.Duration   // timeout of a request, default 5s
+	Spool     tsfio.Directory // spool directory buffering entries on disk, empty for the in-memory buffer
+	SpoolSize int64           // maximum size of the spool in bytes, default 64 MiB
+}
+
+// Values of HTTPArgs disabling retries and backoff, since zero values are replaced by defaults
+const (
+	NoRetry   int           = -1 // post each batch once without retry
+	NoBackoff time.Duration = -1 // retry without waiting
+)
+
+// HTTPSink is a Sink posting batches of entries encoded in the format of the logger as
+// NDJSON to a URL. A batch is posted, if it reaches the batch size or the flush interval
+// passes. If a request times out, fails or the response has a 5xx status code, the
+// batch is retried with exponential backoff. If the buffer is full or a batch fails
+// after all retries, entries are dropped and counted by Dropped. Batches rejected with other
+// status codes are dropped without retry. Close posts the pending entries. With a spool,
+// entries are buffered on disk instead and a batch failing after all retries remains in the
+// spool and is posted again after the flush interval.
+type HTTPSink struct {
+	args    HTTPArgs              // configuration
+	client  *http.Client          // HTTP client
+	enc     Encoder               // encodes an entry, nil for the line of the logger
+	body    func([][]byte) []byte // encodes a batch into the request body
+	ctype   string                // content type of the request body
+	mu      sync.RWMutex          // mutex for closed and the spool
+	closed  bool                  // true, if the sink is closed
+	ch      chan []byte           // buffered entries, nil with a spool
+	spool   *spool                // disk-backed buffer, nil for the in-memory buffer
+	err     error                 // first error of the spool in the background
+	wake    chan struct{}         // signals spooled entries to the sender
+	quit    chan struct{}         // closed to stop the sink
+	done    chan struct{}         // closed, when the sink stopped
+	dropped atomic.Uint64         // number of dropped entries
+}
+
+// NewHTTPSink creates a new HTTPSink with configuration a and starts posting batches. It
+// returns an error, if a is nil, the URL is invalid, a value is negative other than
+// NoRetry and NoBackoff or the spool directory cannot be read.
+func NewHTTPSink(a *HTTPArgs) (*HTTPSink, error) {
+	return newHTTPSink(a, ndjsonBody, ndjson)
+}
+
+// newHTTPSink creates a new HTTPSink with configuration a, which encodes batches with body
+// and content type ctype. It returns an error, if a is nil, the URL is invalid, a value is
+// negative other than NoRetry and NoBackoff or the spool directory cannot be read.
+func newHTTPSink(a *HTTPArgs, body func([][]byte) []byte, ctype string) (*HTTPSink, error) {
+	// Return an error, if a is nil
+	if a == nil {
+		return nil, tserr.NilPtr()
+	}
+	// Return an error, if the URL is invalid
+	if u, err := url.Parse(a.URL); (err != nil) || ((u.Scheme != "http") && (u.Scheme != "https")) {
+		return nil, tserr.Check(&tserr.CheckArgs{F: a.URL, Err: fmt.Errorf("invalid http URL")})
+	}
+	// Return an error, if a value is negative other than NoRetry and NoBackoff
+	if (a.BatchSize < 0) || (a.Interval < 0) || (a.Buffer < 0) || (a.Retries < NoRetry) || (a.Backoff < NoBackoff) || (a.Timeout < 0) || (a.SpoolSize < 0) {
+		return nil, tserr.NotExistent(fmt.Sprintf("negative value in HTTP sink configuration %+v", *a))
+	}
+	// Copy a and replace zero values by defaults
+	c := *a
+	c.BatchSize = orDefault(c.BatchSize, defaultHTTPBatch)
+	c.Interval = orDefault(c.Interval, defaultHTTPInterval)
+	c.Buffer = orDefault(c.Buffer, defaultHTTPBuffer)
+	c.Retries = orDefault(c.Retries, defaultHTTPRetries)
+	c.Backoff = orDefault(c.Backoff, defaultHTTPBackoff)
+	// Disable retries and backoff, if requested
+	if c.Retries == NoRetry {
+		c.Retries = 0
+	}
+	if c.Backoff == NoBackoff {
+		c.Backoff = 0
+	}
+	c.Timeout = orDefault(c.Timeout, defaultHTTPTimeout)
+	c.SpoolSize = orDefault(c.SpoolSize, defaultHTTPSpool)
+	// Create the sink
+	s := &HTTPSink{
+		args:   c,
+		client: &http.Client{Timeout: c.Timeout},
+		body:   body,
+		ctype:  ctype,
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	// Open the spool and start posting its batches, if a spool directory is set
+	if c.Spool != "" {
+		var err error
+		if s.spool, err = newSpool(c.Spool, c.SpoolSize, &s.dropped); err != nil {
+			return nil, err
+		}
+		s.wake = make(chan struct{}, 1)
+		go s.runSpool()
+		return s, nil
+	}
+	// Start posting batches
+	s.ch = make(chan []byte, c.Buffer)
+	go s.run()
+	// Return the sink
+	return s, nil
+}
+
+// Dropped returns the number of entries dropped by s, because the buffer was full or
+// posting failed after all retries.
+func (s *HTTPSink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Log buffers line. If the buffer is full, line is dropped. It returns nil, since
+// dropped entries are counted. If the sink has its own encoder, e is encoded instead
+// of using line and an error is returned, if encoding fails. With a spool, it returns
+// an error, if writing to the spool fails.
+func (s *HTTPSink) Log(e *Entry, line []byte) error {
+	// Encode e, if the sink has an encoder
+	if s.enc != nil {
+		var err error
+		if line, err = s.enc(e); err != nil {
+			return err
+		}
+	}
+	// Append line to the spool, if any
+	if s.spool != nil {
+		return s.spoolLine(line)
+	}
+	// Lock closed for reading, so Close waits for line to be buffered
+	s.mu.RLock()
+	// Unlock closed on return
+	defer s.mu.RUnlock()
+	// Drop line, if the sink is closed
+	if s.closed {
+		s.dropped.Add(1)
+		return nil
+	}
+	// Buffer line or drop it, if the buffer is full
+	select {
+	case s.ch <- line:
+	default:
+		s.dropped.Add(1)
+	}
+	// Return nil
+	return nil
+}
+
+// Close posts the pending entries and stops the sink. Entries dropped during the lifetime
+// of the sink are not reported as error, but retrieved with Dropped. With a spool, entries,
+// which cannot be posted, remain in the spool. It returns an error, if the spool failed or
+// closing the spool fails.
+func (s *HTTPSink) Close() error {
+	// Lock closed
+	s.mu.Lock()
+	// Wait for the sink to stop and return nil, if s is already closed
+	if s.closed {
+		s.mu.Unlock()
+		<-s.done
+		return nil
+	}
+	// Stop the sink
+	s.closed = true
+	close(s.quit)
+	s.mu.Unlock()
+	// Wait for the sink to post the pending entries
+	<-s.done
+	// Return nil, if there is no spool
+	if s.spool == nil {
+		return nil
+	}
+	// Lock the spool
+	s.mu.Lock()
+	// Unlock the spool on return
+	defer s.mu.Unlock()
+	// Close the spool and return the first error of the spool, if any
+	errs := s.err
+	if err := s.spool.close(); errs == nil {
+		errs = err
+	}
+	return errs
+}
+
+// spoolLine appends line to the spool and signals the sender. If the sink is closed, line is
+// dropped. It returns an error, if writing to the spool fails.
+func (s *HTTPSink) spoolLine(line []byte) error {
+	// Lock closed and the spool
+	s.mu.Lock()
+	// Drop line, if the sink is closed
+	if s.closed {
+		s.mu.Unlock()
+		s.dropped.Add(1)
+		return nil
+	}
+	// Append line with newline to the spool
+	err := s.spool.append(append(append(make([]byte, 0, len(line)+1), line...), '\n'))
+	s.mu.Unlock()
+	// Signal the sender without blocking
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	// Return the error of the spool, if any
+	return err
+}
+
+// run collects buffered entries into batches and posts them by size or interval until
+// the sink is stopped. Then, it posts the pending entries.
+func (s *HTTPSink) run() {
+	// Signal that the sink stopped on return
+	defer close(s.done)
+	// Create the flush ticker
+	tick := time.NewTicker(s.args.Interval)
+	defer tick.Stop()
+	// batch holds the current batch
+	batch := make([][]byte, 0, s.args.BatchSize)
+	// Collect and post batches
+	for {
+		select {
+		case line := <-s.ch:
+			// Append line and post the batch, if it reached the batch size
+			if batch = append(batch, line); len(batch) >= s.args.BatchSize {
+				batch = s.post(batch)
+			}
+		case <-tick.C:
+			// Post a non-empty batch
+			batch = s.post(batch)
+		case <-s.quit:
+			// Post the pending entries in batches and return
+			for {
+				select {
+				case line := <-s.ch:
+					if batch = append(batch, line); len(batch) >= s.args.BatchSize {
+						batch = s.post(batch)
+					}
+				default:
+					s.post(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// post posts batch with retries. It drops the batch, if posting fails after all retries or
+// is rejected. It returns the emptied batch for reuse.
+func (s *HTTPSink) post(batch [][]byte) [][]byte {
+	// Return, if batch is empty
+	if len(batch) == 0 {
+		return batch
+	}
+	// Drop the batch, if posting fails after all retries or is rejected
+	if _, ok := s.deliver(s.body(batch)); !ok {
+		s.dropped.Add(uint64(len(batch)))
+	}
+	// Return the emptied batch
+	return batch[:0]
+}
+
+// runSpool posts the entries of the spool in batches by size or interval until the sink is
+// stopped. If a batch fails after all retries, posting is resumed after the interval. Then,
+// it posts the remaining entries.
+func (s *HTTPSink) runSpool() {
+	// Signal that the sink stopped on return
+	defer close(s.done)
+	// Create the flush ticker
+	tick := time.NewTicker(s.args.Interval)
+	defer tick.Stop()
+	// ok is false, if a batch failed, until the next interval
+	ok := true
+	// Post batches of the spool
+	for {
+		select {
+		case <-s.wake:
+			// Post full batches, if no batch failed
+			if ok {
+				ok = s.postSpool(true)
+			}
+		case <-tick.C:
+			// Post all entries
+			ok = s.postSpool(false)
+		case <-s.quit:
+			// Post the remaining entries and return
+			s.postSpool(false)
+			return
+		}
+	}
+}
+
+// postSpool posts the entries of the spool in batches. If full is true, only full batches
+// are posted. Batches are removed from the spool, if posted or rejected. Rejected batches are
+// counted as dropped. It returns false, if reading the spool fails or a batch fails after all
+// retries.
+func (s *HTTPSink) postSpool(full bool) bool {
+	for {
+		// Retrieve the next batch and its generation
+		s.mu.Lock()
+		batch, err := s.spool.batch(s.args.BatchSize)
+		gen := s.spool.gen
+		// Keep the first error, if reading the spool fails
+		if (err != nil) && (s.err == nil) {
+			s.err = err
+		}
+		s.mu.Unlock()
+		// Return false, if reading the spool fails
+		if err != nil {
+			return false
+		}
+		// Return true, if the spool is empty or holds no full batch, if requested
+		if (len(batch) == 0) || (full && (len(batch) < s.args.BatchSize)) {
+			return true
+		}
+		// Remove the newlines
+		for i := range batch {
+			batch[i] = bytes.TrimSuffix(batch[i], []byte("\n"))
+		}
+		// Post the batch and return false, if it fails after all retries
+		retry, ok := s.deliver(s.body(batch))
+		if retry {
+			return false
+		}
+		// Count the batch as dropped, if rejected
+		if !ok {
+			s.dropped.Add(uint64(len(batch)))
+		}
+		// Remove the batch from the spool, if it was not dropped meanwhile
+		s.mu.Lock()
+		if s.spool.gen == gen {
+			for range batch {
+				s.spool.next()
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// deliver posts body b with exponential backoff. It returns true for retry, if posting
+// fails after all retries. It returns true for ok, if posting succeeds.
+func (s *HTTPSink) deliver(b []byte) (retry bool, ok bool) {
+	for i, backoff := 0, s.args.Backoff; ; i, backoff = i+1, 2*backoff {
+		// Post the request body
+		retry, ok := s.send(b)
+		// Return, if posting succeeds, is rejected or all retries failed
+		if !retry || (i >= s.args.Retries) {
+			return retry, ok
+		}
+		// Wait for the backoff
+		time.Sleep(backoff)
+	}
+}
+
+// send posts body b. It returns true for retry, if the request fails or times out or the
+// response has a 5xx status code. It returns true for ok, if the response has a 2xx status code.
+func (s *HTTPSink) send(b []byte) (retry bool, ok bool) {
+	// Post the request body
+	resp, err := s.client.Post(s.args.URL, s.ctype, bytes.NewReader(b))
+	// Retry, if the request fails or times out
+	if err != nil {
+		return true, false
+	}
+	// Drain and close the response body
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	// Retry for 5xx status codes and succeed for 2xx status codes
+	return resp.StatusCode >= 500, (resp.StatusCode >= 200) && (resp.StatusCode <= 299)
+}
+
+// ndjsonBody returns the lines of batch separated and terminated by newlines.
+func ndjsonBody(batch [][]byte) []byte {
+	// b holds the request body
+	var b bytes.Buffer
+	// Append each line with newline
+	for _, line := range batch {
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	// Return the request body
+	return b.Bytes()
+}
+
+// orDefault returns v or the default d, if v is zero.
+func orDefault[T int | int64 | time.Duration](v, d T) T {
+	// Return the default, if v is zero
+	if v == 0 {
+		return d
+	}
+	// Return v
+	return v
+}
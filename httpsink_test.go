@@ -0,0 +1,271 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"io"                // io
+	"net/http"          // http
+	"net/http/httptest" // httptest
+	"strings"           // strings
+	"sync"              // sync
+	"testing"           // testing
+	"time"              // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// httprec records the requests received by a test server.
+type httprec struct {
+	mu     sync.Mutex    // mutex for the records
+	status func(int) int // returns the status code for the n-th request
+	block  chan struct{} // blocks requests until closed, if not nil
+	n      int           // number of requests
+	lines  []string      // lines of accepted requests
+	ctype  string        // content type of the last request
+}
+
+// ServeHTTP records request r and responds with the status code for the request.
+func (h *httprec) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Block, if requested
+	if h.block != nil {
+		<-h.block
+	}
+	// Read the request body
+	b, _ := io.ReadAll(r.Body)
+	// Lock the records
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// Retrieve the status code for the request
+	h.n++
+	code := h.status(h.n)
+	// Record the content type
+	h.ctype = r.Header.Get("Content-Type")
+	// Record the lines of an accepted request
+	if code == http.StatusOK {
+		h.lines = append(h.lines, strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")...)
+	}
+	// Respond with the status code
+	w.WriteHeader(code)
+}
+
+// requests returns the number of requests and the recorded lines.
+func (h *httprec) requests() (int, []string) {
+	// Lock the records
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// Return the number of requests and a copy of the lines
+	return h.n, append([]string(nil), h.lines...)
+}
+
+// TestHTTPSink logs three entries with a batch size of two to a test server, which
+// responds to the first request with 503. The test fails if the entries are not
+// posted as NDJSON in two batches after one retry.
+func TestHTTPSink(t *testing.T) {
+	// Create the test server, which fails the first request
+	h := &httprec{status: func(n int) int {
+		if n == 1 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	}}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	// Log three entries with a batch size of two
+	s := testHTTPSink(t, &HTTPArgs{URL: srv.URL, BatchSize: 2, Interval: time.Hour, Backoff: time.Millisecond}, 3)
+	// Retrieve the requests
+	n, lines := h.requests()
+	// Record an error, if the number of requests is not three
+	if n != 3 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "requests", Actual: int64(n), Want: 3}))
+	}
+	// Record an error, if the number of lines is not three
+	if len(lines) != 3 {
+		t.Fatal(tserr.Equal(&tserr.EqualArgs{Var: "lines", Actual: int64(len(lines)), Want: 3}))
+	}
+	// Record an error, if a line is not the expected log entry
+	for _, line := range lines {
+		if !strings.Contains(line, `"message":"test"`) {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: `"message":"test"`, Y: line}))
+		}
+	}
+	// Record an error, if the content type is not NDJSON
+	if h.ctype != ndjson {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: ndjson, Y: h.ctype}))
+	}
+	// Record an error, if entries were dropped
+	if d := s.Dropped(); d != 0 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "Dropped", Actual: int64(d), Want: 0}))
+	}
+}
+
+// TestHTTPSinkInterval logs an entry to a test server with a short flush interval. The
+// test fails if the entry is not posted before the sink is closed.
+func TestHTTPSinkInterval(t *testing.T) {
+	// Create the test server
+	h := &httprec{status: func(int) int { return http.StatusOK }}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	// Create the sink with a flush interval of 10ms
+	s, err := NewHTTPSink(&HTTPArgs{URL: srv.URL, Interval: 10 * time.Millisecond})
+	// Stop execution, if creating the sink fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New HTTP sink", Fn: srv.URL, Err: err}))
+	}
+	// Close the sink on return
+	defer s.Close()
+	// Log an entry
+	s.Log(nil, []byte(`{"log":{}}`))
+	// Wait up to one second for the entry to be posted
+	for i := 0; i < 100; i++ {
+		if _, lines := h.requests(); len(lines) == 1 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Record an error, if the entry was not posted
+	t.Error(tserr.NilFailed("post by interval"))
+}
+
+// TestHTTPSinkRetries logs an entry to a test server, which always responds with 500.
+// The test fails if the batch is not retried twice and dropped afterwards.
+func TestHTTPSinkRetries(t *testing.T) {
+	// Create the test server, which fails all requests
+	h := &httprec{status: func(int) int { return http.StatusInternalServerError }}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	// Log an entry with two retries
+	s := testHTTPSink(t, &HTTPArgs{URL: srv.URL, Retries: 2, Backoff: time.Millisecond}, 1)
+	// Record an error, if the number of requests is not three
+	if n, _ := h.requests(); n != 3 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "requests", Actual: int64(n), Want: 3}))
+	}
+	// Record an error, if the entry was not dropped
+	if d := s.Dropped(); d != 1 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "Dropped", Actual: int64(d), Want: 1}))
+	}
+}
+
+// TestHTTPSinkDrop logs entries to a blocked test server with a buffer of one entry.
+// The test fails if no entries are dropped or Close reports them as error.
+func TestHTTPSinkDrop(t *testing.T) {
+	// Create the test server, which blocks requests
+	h := &httprec{status: func(int) int { return http.StatusOK }, block: make(chan struct{})}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	// Create the sink with a buffer of one entry and a batch size of one
+	s, err := NewHTTPSink(&HTTPArgs{URL: srv.URL, BatchSize: 1, Buffer: 1})
+	// Stop execution, if creating the sink fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New HTTP sink", Fn: srv.URL, Err: err}))
+	}
+	// Log entries
+	for i := 0; i < 10; i++ {
+		s.Log(nil, []byte(`{"log":{}}`))
+	}
+	// Record an error, if no entries were dropped
+	if s.Dropped() == 0 {
+		t.Error(tserr.NilFailed("Dropped"))
+	}
+	// Unblock the test server
+	close(h.block)
+	// Record an error, if Close returns an error
+	if err := s.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: srv.URL, Err: err}))
+	}
+}
+
+// TestHTTPSinkNoRetry logs an entry to a test server, which always responds with 500, with
+// retries disabled. The test fails if the batch is posted more than once or not dropped.
+func TestHTTPSinkNoRetry(t *testing.T) {
+	// Create the test server, which fails all requests
+	h := &httprec{status: func(int) int { return http.StatusInternalServerError }}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	// Log an entry without retries and backoff
+	s := testHTTPSink(t, &HTTPArgs{URL: srv.URL, Retries: NoRetry, Backoff: NoBackoff}, 1)
+	// Record an error, if the number of requests is not one
+	if n, _ := h.requests(); n != 1 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "requests", Actual: int64(n), Want: 1}))
+	}
+	// Record an error, if the entry was not dropped
+	if d := s.Dropped(); d != 1 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "Dropped", Actual: int64(d), Want: 1}))
+	}
+}
+
+// TestHTTPSinkClose logs entries concurrently to closing the sink. The test fails if an
+// entry is neither posted nor counted as dropped.
+func TestHTTPSinkClose(t *testing.T) {
+	// Create the test server, which accepts all requests
+	h := &httprec{status: func(int) int { return http.StatusOK }}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	// Create the sink
+	s, err := NewHTTPSink(&HTTPArgs{URL: srv.URL})
+	// Stop execution, if creating the sink fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New HTTP sink", Fn: srv.URL, Err: err}))
+	}
+	// Log entries in goroutines
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Log(nil, []byte(`{"log":{}}`))
+			}
+		}()
+	}
+	// Close the sink concurrently and wait for the goroutines
+	s.Close()
+	wg.Wait()
+	// Record an error, if entries are neither posted nor dropped
+	if _, lines := h.requests(); uint64(len(lines))+s.Dropped() != 400 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "posted and dropped entries", Actual: int64(uint64(len(lines)) + s.Dropped()), Want: 400}))
+	}
+}
+
+// TestHTTPSinkErr creates HTTP sinks with invalid configurations. The test fails
+// if NewHTTPSink does not return an error.
+func TestHTTPSinkErr(t *testing.T) {
+	// Iterate invalid configurations
+	for _, a := range []*HTTPArgs{nil, {URL: "localhost:8080"}, {URL: "http://localhost", Retries: -2}, {URL: "http://localhost", BatchSize: -1}, {URL: "http://localhost", SpoolSize: -1}} {
+		// Record an error, if NewHTTPSink returns nil
+		if _, err := NewHTTPSink(a); err == nil {
+			t.Error(tserr.NilFailed("New HTTP sink"))
+		}
+	}
+}
+
+// testHTTPSink creates an HTTP sink with configuration a and logs n entries with message
+// "test" at Info level. It closes the logger afterwards and returns the sink.
+func testHTTPSink(t *testing.T, a *HTTPArgs, n int) *HTTPSink {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Create the HTTP sink s
+	s, err := NewHTTPSink(a)
+	// Stop execution, if creating the sink fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New HTTP sink", Fn: a.URL, Err: err}))
+	}
+	// Create new logger lg with sink s
+	lg := New()
+	if err := lg.SetSink(s); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set sink", Fn: a.URL, Err: err}))
+	}
+	// Log n entries
+	for i := 0; i < n; i++ {
+		if err := lg.Info("test"); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: a.URL, Err: err}))
+		}
+	}
+	// Close the logger, which posts the pending entries
+	lg.Close()
+	// Return the sink
+	return s
+}
@@ -0,0 +1,290 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+
+//go:build linux
+
+package tslog
+
+// Import standard library packages, tserr and unix.
+import (
+	"bytes"           // bytes
+	"encoding/binary" // binary
+	"errors"          // errors
+	"fmt"             // fmt
+	"net"             // net
+	"os"              // os
+	"path/filepath"   // filepath
+	"sort"            // sort
+	"strings"         // strings
+	"sync"            // sync
+	"syscall"         // syscall
+
+	"github.com/thorstenrie/tserr" // tserr
+	"golang.org/x/sys/unix"        // unix
+)
+
+// Path of the native journal protocol socket of systemd-journald
+const JournalSocket string = "/run/systemd/journal/socket"
+
+// Defaults for journald
+const (
+	// Maximum length of a journal field name
+	journalFieldLen int = 64
+	// Name of memory files for large payloads passed as file descriptor
+	journalMemfd string = "tslog-journal"
+	// Prefix of journal field names of fields colliding with reserved journal fields
+	journalPrefix string = "TSLOG_"
+)
+
+// journalReserved holds the journal fields written by the sink or with a meaning defined by journald.
+var journalReserved = map[string]bool{
+	"MESSAGE":            true,
+	"MESSAGE_ID":         true,
+	"PRIORITY":           true,
+	"CODE_FILE":          true,
+	"CODE_LINE":          true,
+	"CODE_FUNC":          true,
+	"ERRNO":              true,
+	"INVOCATION_ID":      true,
+	"USER_INVOCATION_ID": true,
+	"SYSLOG_FACILITY":    true,
+	"SYSLOG_IDENTIFIER":  true,
+	"SYSLOG_PID":         true,
+	"SYSLOG_TIMESTAMP":   true,
+	"SYSLOG_RAW":         true,
+	"DOCUMENTATION":      true,
+	"TID":                true,
+	"UNIT":               true,
+	"USER_UNIT":          true,
+}
+
+// JournaldSink is a Sink writing entries to systemd-journald with the native journal protocol
+// over a unix datagram socket. Each entry is written with the journal fields PRIORITY,
+// MESSAGE, SYSLOG_IDENTIFIER and TSLOG_LEVEL. The fields of the entry are written as journal
+// fields with upper case names, e.g. field tenant as TENANT. Characters other than A-Z, 0-9
+// and underscore are replaced by an underscore and leading underscores and digits are removed.
+// Names of reserved journal fields, e.g. MESSAGE or PRIORITY, and names starting with TSLOG_
+// are prefixed with TSLOG_, so fields cannot overwrite them. If a payload exceeds the maximum
+// datagram size, it is written to a sealed memory file, which is passed to journald as file
+// descriptor.
+type JournaldSink struct {
+	mu    sync.Mutex    // mutex for the connection
+	addr  *net.UnixAddr // address of the journal socket
+	ident string        // syslog identifier
+	conn  *net.UnixConn // connection to the journal socket
+}
+
+// NewJournaldSink creates a new JournaldSink for the journal socket path with syslog
+// identifier ident. If path is empty, JournalSocket is used. If ident is empty, the
+// program name is used. It returns an error, if connecting to the journal socket fails.
+func NewJournaldSink(path, ident string) (*JournaldSink, error) {
+	// Set the path to the default journal socket, if empty
+	if path == "" {
+		path = JournalSocket
+	}
+	// Set the syslog identifier to the program name, if empty
+	if ident == "" {
+		ident = filepath.Base(os.Args[0])
+	}
+	// Create the sink
+	s := &JournaldSink{addr: &net.UnixAddr{Name: path, Net: "unixgram"}, ident: ident}
+	// Connect to the journal socket
+	if err := s.dial(); err != nil {
+		return nil, err
+	}
+	// Return the sink
+	return s, nil
+}
+
+// Log writes entry e to the journal. It returns an error, if writing fails after
+// re-establishing the connection.
+func (s *JournaldSink) Log(e *Entry, _ []byte) error {
+	// Encode the payload
+	p := s.payload(e)
+	// Lock the connection
+	s.mu.Lock()
+	// Unlock the connection on return
+	defer s.mu.Unlock()
+	// Write the payload, if connected
+	if s.conn != nil {
+		if err := s.send(p); err == nil {
+			return nil
+		}
+		// Close the failed connection
+		s.conn.Close()
+		s.conn = nil
+	}
+	// Re-establish the connection
+	if err := s.dial(); err != nil {
+		return err
+	}
+	// Write the payload
+	return s.send(p)
+}
+
+// Close closes the connection to the journal socket.
+func (s *JournaldSink) Close() error {
+	// Lock the connection
+	s.mu.Lock()
+	// Unlock the connection on return
+	defer s.mu.Unlock()
+	// Return nil, if not connected
+	if s.conn == nil {
+		return nil
+	}
+	// Close the connection
+	err := s.conn.Close()
+	s.conn = nil
+	// Return an error, if closing fails
+	if err != nil {
+		return tserr.Op(&tserr.OpArgs{Op: "close journal socket", Fn: s.addr.Name, Err: err})
+	}
+	// Return nil
+	return nil
+}
+
+// dial connects to the journal socket. It returns an error, if connecting fails.
+func (s *JournaldSink) dial() error {
+	// Connect to the journal socket
+	c, err := net.DialUnix("unixgram", nil, s.addr)
+	// Return an error, if connecting fails
+	if err != nil {
+		return tserr.Op(&tserr.OpArgs{Op: "connect to journal socket", Fn: s.addr.Name, Err: err})
+	}
+	// Keep the connection
+	s.conn = c
+	// Return nil
+	return nil
+}
+
+// send writes payload p as datagram. If p exceeds the maximum datagram size, it writes p to
+// a sealed memory file and passes its file descriptor. It returns an error, if writing fails.
+func (s *JournaldSink) send(p []byte) error {
+	// Write p as datagram
+	_, err := s.conn.Write(p)
+	// Return nil, if writing succeeds
+	if err == nil {
+		return nil
+	}
+	// Return an error, if p does not exceed the maximum datagram size
+	if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+		return tserr.Op(&tserr.OpArgs{Op: "write to journal socket", Fn: s.addr.Name, Err: err})
+	}
+	// Create a sealed memory file holding p
+	f, err := memfd(p)
+	if err != nil {
+		return err
+	}
+	// Close the memory file on return
+	defer f.Close()
+	// Retrieve the raw connection
+	rc, err := s.conn.SyscallConn()
+	if err != nil {
+		return tserr.Op(&tserr.OpArgs{Op: "write to journal socket", Fn: s.addr.Name, Err: err})
+	}
+	// Pass the file descriptor of the temporary file on the connected socket
+	var errs error
+	if err := rc.Write(func(fd uintptr) bool {
+		errs = syscall.Sendmsg(int(fd), nil, syscall.UnixRights(int(f.Fd())), nil, 0)
+		return !errors.Is(errs, syscall.EAGAIN)
+	}); err != nil {
+		errs = err
+	}
+	// Return an error, if passing the file descriptor fails
+	if errs != nil {
+		return tserr.Op(&tserr.OpArgs{Op: "write to journal socket", Fn: s.addr.Name, Err: errs})
+	}
+	// Return nil
+	return nil
+}
+
+// memfd returns a memory file holding payload p, which is sealed against modification. It
+// returns an error, if creating, writing or sealing the memory file fails.
+func memfd(p []byte) (*os.File, error) {
+	// Create the memory file with sealing allowed
+	fd, err := unix.MemfdCreate(journalMemfd, unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return nil, tserr.Op(&tserr.OpArgs{Op: "create memfd", Fn: journalMemfd, Err: err})
+	}
+	f := os.NewFile(uintptr(fd), journalMemfd)
+	// Close the memory file and return an error, if writing p fails
+	if _, err := f.Write(p); err != nil {
+		f.Close()
+		return nil, tserr.Op(&tserr.OpArgs{Op: "write memfd", Fn: journalMemfd, Err: err})
+	}
+	// Close the memory file and return an error, if sealing fails
+	if _, err := unix.FcntlInt(f.Fd(), unix.F_ADD_SEALS, unix.F_SEAL_SEAL|unix.F_SEAL_SHRINK|unix.F_SEAL_GROW|unix.F_SEAL_WRITE); err != nil {
+		f.Close()
+		return nil, tserr.Op(&tserr.OpArgs{Op: "seal memfd", Fn: journalMemfd, Err: err})
+	}
+	// Return the memory file
+	return f, nil
+}
+
+// payload returns the native journal protocol payload for entry e.
+func (s *JournaldSink) payload(e *Entry) []byte {
+	// b holds the payload
+	var b bytes.Buffer
+	// Retrieve string representation of the log level
+	ls, _ := level(e.Level)
+	// Append the journal fields of the entry
+	journalField(&b, "PRIORITY", fmt.Sprint(severity(e.Level)))
+	journalField(&b, "MESSAGE", e.Message)
+	journalField(&b, "SYSLOG_IDENTIFIER", s.ident)
+	journalField(&b, "TSLOG_LEVEL", ls)
+	// Retrieve sorted keys of the fields
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	// Append the fields
+	for _, k := range keys {
+		// Append the field, if its name is valid
+		if n := journalName(k); n != "" {
+			journalField(&b, n, fmt.Sprint(e.Fields[k]))
+		}
+	}
+	// Return the payload
+	return b.Bytes()
+}
+
+// journalField appends journal field name with value v to b. Values containing a newline
+// are appended with their length as little endian 64 bit integer.
+func journalField(b *bytes.Buffer, name, v string) {
+	// Append name=v, if v does not contain a newline
+	if !strings.Contains(v, "\n") {
+		b.WriteString(name + "=" + v + "\n")
+		return
+	}
+	// Append name, the length of v, v and a newline
+	b.WriteString(name + "\n")
+	binary.Write(b, binary.LittleEndian, uint64(len(v)))
+	b.WriteString(v + "\n")
+}
+
+// journalName returns the journal field name for field key k. Names colliding with reserved
+// journal fields or starting with TSLOG_ are prefixed with TSLOG_. It returns an empty
+// string, if no valid name remains.
+func journalName(k string) string {
+	// Replace characters other than A-Z, 0-9 and underscore by an underscore
+	n := strings.Map(func(r rune) rune {
+		if ((r >= 'A') && (r <= 'Z')) || ((r >= '0') && (r <= '9')) || (r == '_') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(k))
+	// Remove leading underscores and digits
+	n = strings.TrimLeft(n, "_0123456789")
+	// Prefix the name, if it collides with a reserved journal field or the fields of tslog
+	if journalReserved[n] || strings.HasPrefix(n, journalPrefix) {
+		n = journalPrefix + n
+	}
+	// Truncate the name to the maximum length
+	if len(n) > journalFieldLen {
+		n = n[:journalFieldLen]
+	}
+	// Return the name
+	return n
+}
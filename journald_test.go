@@ -0,0 +1,216 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+
+//go:build linux
+
+package tslog
+
+// Import standard library packages, tserr, tsfio and unix.
+import (
+	"bytes"           // bytes
+	"encoding/binary" // binary
+	"io"              // io
+	"net"             // net
+	"os"              // os
+	"path/filepath"   // filepath
+	"strings"         // strings
+	"syscall"         // syscall
+	"testing"         // testing
+	"time"            // time
+
+	"github.com/thorstenrie/tserr" // tserr
+	"github.com/thorstenrie/tsfio" // tsfio
+	"golang.org/x/sys/unix"        // unix
+)
+
+// TestJournald logs an entry with fields to a fake journal socket. The test fails
+// if the received journal fields do not match the expected fields.
+func TestJournald(t *testing.T) {
+	// Create a fake journal socket
+	c, p, d := testJournalSocket(t)
+	// Log an entry at Warn level with fields
+	testJournald(t, p, "multi\nline")
+	// Receive the payload
+	f := testJournalFields(t, c)
+	// Close the fake journal socket
+	testJournalClose(t, c, p, d)
+	// Iterate expected journal fields
+	for k, want := range map[string]string{"PRIORITY": "4", "MESSAGE": "multi\nline", "SYSLOG_IDENTIFIER": "test", "TSLOG_LEVEL": "warn", "TENANT_ID": "a", "X": "1", "TSLOG_MESSAGE": "m", "TSLOG_TSLOG_LEVEL": "l"} {
+		// Record an error, if the journal field does not match
+		if f[k] != want {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: want, Y: f[k]}))
+		}
+	}
+}
+
+// TestJournaldLarge logs an entry exceeding the maximum datagram size to a fake journal socket.
+// The test fails if the payload is not passed as file descriptor.
+func TestJournaldLarge(t *testing.T) {
+	// Create a fake journal socket
+	c, p, d := testJournalSocket(t)
+	// Log an entry with a large message
+	msg := strings.Repeat("a", 8<<20)
+	testJournald(t, p, msg)
+	// Receive the payload
+	f := testJournalFields(t, c)
+	// Close the fake journal socket
+	testJournalClose(t, c, p, d)
+	// Record an error, if the message does not match
+	if len(f["MESSAGE"]) != len(msg) {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "Message length", Actual: int64(len(f["MESSAGE"])), Want: int64(len(msg))}))
+	}
+}
+
+// TestJournaldErr creates a journald sink for a non existent socket. The test fails
+// if NewJournaldSink does not return an error.
+func TestJournaldErr(t *testing.T) {
+	// Record an error, if NewJournaldSink returns nil
+	if _, err := NewJournaldSink(filepath.Join(os.TempDir(), "tslog_nonexistent"), ""); err == nil {
+		t.Error(tserr.NilFailed("New journald sink"))
+	}
+}
+
+// testJournald creates a journald sink for the journal socket p and logs an entry with message msg
+// and fields at Warn level. It closes the logger afterwards.
+func testJournald(t *testing.T, p, msg string) {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Create the journald sink s
+	s, err := NewJournaldSink(p, "test")
+	// Stop execution, if creating the sink fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New journald sink", Fn: p, Err: err}))
+	}
+	// Create new logger lg with sink s
+	lg := New()
+	if err := lg.SetSink(s); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set sink", Fn: p, Err: err}))
+	}
+	// Add processor setting fields with names to be converted
+	lg.AddProcessor(func(e *Entry) bool {
+		e.SetField("tenant-id", "a")
+		e.SetField("_1x", 1)
+		e.SetField("message", "m")
+		e.SetField("tslog_level", "l")
+		return true
+	})
+	// Log the entry
+	if err := lg.Warn(msg); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Warn", Fn: p, Err: err}))
+	}
+	// Close the logger
+	if err := lg.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: p, Err: err}))
+	}
+}
+
+// testJournalSocket creates a fake journal socket in a temporary directory. It returns
+// the socket, its path and the temporary directory.
+func testJournalSocket(t *testing.T) (*net.UnixConn, string, tsfio.Directory) {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Create the temporary directory d
+	d := tmpDir(t)
+	// Listen on a unix datagram socket in d
+	p := filepath.Join(string(d), "socket")
+	c, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: p, Net: "unixgram"})
+	// Stop execution, if listening fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "listen", Fn: p, Err: err}))
+	}
+	// Return the socket, its path and d
+	return c, p, d
+}
+
+// testJournalClose closes the fake journal socket c and removes its path p and directory d.
+func testJournalClose(t *testing.T, c *net.UnixConn, p string, d tsfio.Directory) {
+	// Close the socket
+	c.Close()
+	// Remove the socket path
+	if err := os.Remove(p); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Remove", Fn: p, Err: err}))
+	}
+	// Remove the temporary directory
+	rm(t, d)
+}
+
+// testJournalFields receives a payload on c and returns its journal fields. If the
+// payload is passed as file descriptor, it is read from the file descriptor.
+func testJournalFields(t *testing.T, c *net.UnixConn) map[string]string {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Set a read deadline of one second
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	// Receive the datagram
+	b, oob := make([]byte, 65536), make([]byte, syscall.CmsgSpace(4))
+	n, oobn, _, _, err := c.ReadMsgUnix(b, oob)
+	// Stop execution, if receiving fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "read", Fn: c.LocalAddr().String(), Err: err}))
+	}
+	// Payload from the datagram
+	p := b[:n]
+	// Read the payload from a passed file descriptor, if any
+	if oobn > 0 {
+		// Parse the control message
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if (err != nil) || (len(msgs) != 1) {
+			t.Fatal(tserr.Op(&tserr.OpArgs{Op: "parse control message", Fn: c.LocalAddr().String(), Err: err}))
+		}
+		// Retrieve the file descriptor
+		fds, err := syscall.ParseUnixRights(&msgs[0])
+		if (err != nil) || (len(fds) != 1) {
+			t.Fatal(tserr.Op(&tserr.OpArgs{Op: "parse unix rights", Fn: c.LocalAddr().String(), Err: err}))
+		}
+		// Record an error, if the file is not sealed against writing
+		if seals, err := unix.FcntlInt(uintptr(fds[0]), unix.F_GET_SEALS, 0); (err != nil) || (seals&unix.F_SEAL_WRITE == 0) {
+			t.Error(tserr.NilFailed("seal of payload"))
+		}
+		// Read the payload from the start of the file
+		f := os.NewFile(uintptr(fds[0]), "payload")
+		defer f.Close()
+		if p, err = io.ReadAll(io.NewSectionReader(f, 0, 1<<30)); err != nil {
+			t.Fatal(tserr.Op(&tserr.OpArgs{Op: "read", Fn: "payload", Err: err}))
+		}
+	}
+	// Return the parsed journal fields
+	return testJournalParse(t, p)
+}
+
+// testJournalParse returns the journal fields of native journal protocol payload p.
+// Execution stops, if p is malformed.
+func testJournalParse(t *testing.T, p []byte) map[string]string {
+	// f holds the journal fields
+	f := make(map[string]string)
+	// Iterate p
+	for len(p) > 0 {
+		// Retrieve the next line
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			t.Fatal(tserr.NotExistent("newline in payload"))
+		}
+		line := string(p[:i])
+		p = p[i+1:]
+		// Parse name=value
+		if k, v, ok := strings.Cut(line, "="); ok {
+			f[k] = v
+			continue
+		}
+		// Parse binary value with little endian length
+		if len(p) < 8 {
+			t.Fatal(tserr.NotExistent("length of binary field " + line))
+		}
+		n := binary.LittleEndian.Uint64(p)
+		f[line] = string(p[8 : 8+n])
+		p = p[8+n+1:]
+	}
+	// Return the journal fields
+	return f
+}
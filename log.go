@@ -1,7 +1,8 @@
 // Package tslog implements logging that tries to keep it simple.
 //
 // The tslog package is a logging interface in Go that tries to keep it simple.
-// It provides log levels Trace, Debug, Info, Warn, Error and Fatal.
+// It provides log levels Trace, Debug, Info, Warn, Error and Fatal. Verbosity
+// levels below Trace are provided with V and can be enabled per source file.
 // The log messages are formatted in JSON format to enable parsing.
 // The predefined default logger is set to log to Stdout on Info level. A new
 // logger instance can be created with New(). The output of a logger can be set
@@ -13,8 +14,13 @@
 // that can be found in the LICENSE file.
 package tslog
 
-// Import tsfio.
+// Import standard library packages, tserr and tsfio.
 import (
+	"fmt"     // fmt
+	"strconv" // strconv
+	"strings" // strings
+
+	"github.com/thorstenrie/tserr" // tserr
 	"github.com/thorstenrie/tsfio" // tsfio
 )
 
@@ -42,6 +48,19 @@ const (
 	FatalLevel int = 6
 )
 
+// Format of the log messages
+type Format string
+
+// Formats for log messages
+const (
+	JSONFormat Format = Format("json") // JSON format with root element log
+	GELFFormat Format = Format("gelf") // GELF 1.1 format for Graylog
+	OTelFormat Format = Format("otel") // OTLP/JSON format of the OpenTelemetry logs data model
+	ECSFormat  Format = Format("ecs")  // JSON format of the Elastic Common Schema
+	GCPFormat  Format = Format("gcp")  // JSON format of Google Cloud Logging
+	AWSFormat  Format = Format("aws")  // JSON format of AWS CloudWatch Logs
+)
+
 // Defaults for logging
 const (
 	// Layout for timestamp in the log message
@@ -50,6 +69,8 @@ const (
 	defaultPattern string = "tslog"
 	// Default log level is InfoLevel
 	defaultMinLvl int = InfoLevel
+	// Default format is JSONFormat
+	defaultFormat Format = JSONFormat
 )
 
 // Global logger to provide a predefined standard logger
@@ -69,6 +90,39 @@ func SetLevel(level int) error {
 	return globalLogger.SetLevel(level)
 }
 
+// SetNetOutput sets the logging output of the global predefined standard logger to stream
+// the log messages as lines over network to address, e.g. "tcp" and "localhost:5170". If
+// SetNetOutput returns an error, logging is set to Stdout.
+func SetNetOutput(network, address string) error {
+	return globalLogger.SetNetOutput(network, address)
+}
+
+// SetFormat sets the format of the log messages on the global predefined standard logger.
+// SetFormat returns an error for undefined formats and keeps the current format.
+func SetFormat(f Format) error {
+	return globalLogger.SetFormat(f)
+}
+
+// SetEncoder sets encoder enc for the log messages on the global predefined standard logger.
+// It overrides the format until SetFormat is called. If enc is nil, the encoder of the format is used.
+func SetEncoder(enc Encoder) {
+	globalLogger.SetEncoder(enc)
+}
+
+// ParseLevel returns the log level for its string representation s, e.g. "info", or
+// for its number, e.g. "3". It returns an error, if s is not a defined log level.
+func ParseLevel(s string) (int, error) {
+	// Iterate the level table
+	for lvl := TraceLevel; lvl <= FatalLevel; lvl++ {
+		// Return the log level, if s matches its string representation or number
+		if ls, _ := level(lvl); (strings.EqualFold(s, ls)) || (s == strconv.Itoa(lvl)) {
+			return lvl, nil
+		}
+	}
+	// Return an error, if s is not a defined log level
+	return 0, tserr.NotExistent(fmt.Sprintf("log level %s", s))
+}
+
 // SetOutput sets the logging output to fn. Special loggers are
 // 'stdout' for logging to Stdout (default)
 // 'discard' for no logging
@@ -78,6 +132,27 @@ func SetOutput(fn tsfio.Filename) error {
 	return globalLogger.SetOutput(fn)
 }
 
+// SetVerbosity sets the verbosity for V on the global predefined standard logger.
+// All verbosity levels equal to or lower than v are logged, if the minimum level
+// is Trace. SetVerbosity returns an error, if v is negative and sets the verbosity to zero.
+func SetVerbosity(v int) error {
+	return globalLogger.SetVerbosity(v)
+}
+
+// SetVModule sets per-file verbosity overrides on the global predefined standard logger.
+// The specification spec is a comma separated list of pattern=N, e.g. "conn=3,net/*=2".
+// SetVModule returns an error, if spec is malformed.
+func SetVModule(spec string) error {
+	return globalLogger.SetVModule(spec)
+}
+
+// V returns a Verbose for verbosity level on the global predefined standard logger.
+// Messages are only logged, if the minimum level is Trace and level is enabled by the
+// verbosity or by the vmodule specification for the source file of the caller.
+func V(level int) Verbose {
+	return globalLogger.vlevel(level)
+}
+
 // Trace logs a message at Trace level on the global predefined standard logger.
 // It returns an error if JSON encoding of msg fails.
 func Trace(msg string) error {
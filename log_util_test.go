@@ -5,10 +5,11 @@ package tslog
 
 // Import standard library packages, tserr and tsfio.
 import (
-	"bufio"   // bufio
-	"bytes"   // bytes
-	"os"      // os
-	"testing" // testing
+	"bufio"         // bufio
+	"bytes"         // bytes
+	"encoding/json" // json
+	"os"            // os
+	"testing"       // testing
 
 	"github.com/thorstenrie/tserr" // tserr
 	"github.com/thorstenrie/tsfio" // tsfio
@@ -150,3 +151,102 @@ func evaluate(t *testing.T, fn tsfio.Filename) {
 		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "No. lines", Actual: int64(i), Want: int64(m)}))
 	}
 }
+
+// testMessages returns the log messages in file fn. It removes file fn afterwards.
+// It panics if t is nil. Execution stops if a line cannot be unmarshalled.
+func testMessages(t *testing.T, fn tsfio.Filename) []logmsg {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Create scanner fs on logging output file fn
+	fs := scanner(t, fn)
+	// Remove logging output file fn
+	rm(t, fn)
+	// msgs holds the log messages
+	var msgs []logmsg
+	// Iterate over fs line by line
+	for fs.Scan() {
+		// Unmarshal log message
+		var lmsg logwrap
+		if err := json.Unmarshal(fs.Bytes(), &lmsg); err != nil {
+			// Stop execution if Unmarshal fails
+			t.Fatal(tserr.Op(&tserr.OpArgs{Op: "json unmarshal", Fn: fs.Text(), Err: err}))
+		}
+		// Append log message
+		msgs = append(msgs, lmsg.L)
+	}
+	// Return log messages
+	return msgs
+}
+
+// traceLogger returns a new logger logging at Trace level to a new temporary file.
+// It returns the logger and the filename of the temporary file.
+func traceLogger(t *testing.T) (*Logger, tsfio.Filename) {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Create the temporary file fn
+	fn := tmp(t)
+	// Create new logger lg
+	lg := New()
+	// Set output to temporary file fn
+	if err := lg.SetOutput(fn); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set output", Fn: string(fn), Err: err}))
+	}
+	// Set logging level to Trace
+	if err := lg.SetLevel(TraceLevel); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set level", Fn: string(fn), Err: err}))
+	}
+	// Return logger and filename
+	return lg, fn
+}
+
+// testLines records an error if the number of lines in file fn does not equal n.
+// The file fn is removed afterwards.
+func testLines(t *testing.T, fn tsfio.Filename, n int) {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Create scanner fs on logging output file fn
+	fs := scanner(t, fn)
+	// Remove logging output file fn
+	rm(t, fn)
+	// Count lines
+	i := 0
+	for fs.Scan() {
+		i++
+	}
+	// Record an error if the number of lines does not equal n
+	if i != n {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "No. lines", Actual: int64(i), Want: int64(n)}))
+	}
+}
+
+// testWant records an error, if the levels and messages in file fn do not equal want.
+// The file fn is removed afterwards.
+func testWant(t *testing.T, fn tsfio.Filename, want []logmsg) {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Retrieve the logged messages
+	msgs := testMessages(t, fn)
+	// Stop execution, if the number of logged messages does not equal the expected number
+	if len(msgs) != len(want) {
+		t.Fatal(tserr.Equal(&tserr.EqualArgs{Var: "No. lines", Actual: int64(len(msgs)), Want: int64(len(want))}))
+	}
+	// Iterate the expected messages
+	for i := range want {
+		// Record an error, if the level does not match
+		if msgs[i].Lvl != want[i].Lvl {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: want[i].Lvl, Y: msgs[i].Lvl}))
+		}
+		// Record an error, if the message does not match
+		if msgs[i].Msg != want[i].Msg {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: want[i].Msg, Y: msgs[i].Msg}))
+		}
+	}
+}
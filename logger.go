@@ -5,26 +5,46 @@ package tslog
 
 // Import standard library packages, tserr and tsfio.
 import (
-	"fmt" // fmt
-	"log" // log
-	"os"  // os
+	"fmt"         // fmt
+	"io"          // io
+	"log"         // log
+	"os"          // os
+	"sync"        // sync
+	"sync/atomic" // atomic
 
 	"github.com/thorstenrie/tserr" // tserr
 	"github.com/thorstenrie/tsfio" // tsfio
 )
 
 // Logger contains a log.logger for logging and the minimum level for logging.
-// The minimum level for logging is set with SetLevel.
+// The minimum level for logging is set with SetLevel and the format of the
+// log messages with SetFormat. The verbosity for V is
+// set with SetVerbosity and SetVModule.
 type Logger struct {
-	minLvl int         // minimum level for logging
-	logger *log.Logger // for logging
+	minLvl     int                     // minimum level for logging
+	format     Format                  // format of the log messages
+	encoder    Encoder                 // encoder, nil for the encoder of the format
+	logger     *log.Logger             // for logging
+	verbosity  atomic.Int32            // verbosity for V
+	vmodule    atomic.Pointer[vmodule] // per-file verbosity overrides for V
+	processors []Processor             // processors executed for each entry
+	sampler    *Sampler                // sampler, nil if sampling is disabled
+	limiter    *RateLimiter            // rate limiter, nil if rate limiting is disabled
+	redactor   *Redactor               // redactor, nil if redaction is disabled
+	limits     limits                  // maximum sizes of entries
+	dedup      dedup                   // state for collapsing duplicate messages
+	recorder   *Recorder               // flight recorder, nil if recording is disabled
+	out        sync.RWMutex            // mutex for the output and the configuration of encoding
+	file       *os.File                // output file, nil for Stdout and discard
+	sink       Sink                    // output sink, nil if logging to file, Stdout or discard
 }
 
 // New creates a new logger with default minimum level Info for logging. To alter
-// the minimum level for logging use SetLevel. Logging is set to Stdout. To
+// the minimum level for logging use SetLevel. The log messages are formatted
+// in JSON format. To change the format use SetFormat. Logging is set to Stdout. To
 // change logging output use SetOutput.
 func New() *Logger {
-	return &Logger{minLvl: defaultMinLvl, logger: log.New(os.Stdout, "", 0)}
+	return &Logger{minLvl: defaultMinLvl, format: defaultFormat, logger: log.New(os.Stdout, "", 0)}
 }
 
 // SetLevel sets the logging level. All levels equal or higher than the set level
@@ -58,11 +78,39 @@ func (l *Logger) SetLevel(level int) error {
 	return e
 }
 
+// SetFormat sets the format of the log messages and resets an encoder set with SetEncoder.
+// SetFormat returns an error for undefined formats and keeps the current format.
+func (l *Logger) SetFormat(f Format) error {
+	// Return an error, if f is not in the format table
+	if _, ok := formats[f]; !ok {
+		return tserr.NotExistent(fmt.Sprintf("format %s", f))
+	}
+	// Set format to f and reset the encoder
+	l.out.Lock()
+	l.format, l.encoder = f, nil
+	l.out.Unlock()
+	// Return nil
+	return nil
+}
+
+// SetEncoder sets encoder enc for the log messages, e.g. an encoder with a configuration.
+// It overrides the format until SetFormat is called. If enc is nil, the encoder of the
+// format is used.
+func (l *Logger) SetEncoder(enc Encoder) {
+	// Lock the configuration of encoding
+	l.out.Lock()
+	// Unlock the configuration of encoding on return
+	defer l.out.Unlock()
+	// Set the encoder
+	l.encoder = enc
+}
+
 // SetOutput sets the logging output to fn. Special loggers are
 // 'stdout' for logging to Stdout (default)
 // 'discard' for no logging
 // 'tmp' for logging to tslog_* in the temporary directory
-// If SetOuput returns an error, logging is set to Stdout
+// If SetOuput returns an error, logging is set to Stdout. The previous
+// sink, if any, is closed. The previous output file is not closed.
 func (l *Logger) SetOutput(fn tsfio.Filename) error {
 	// Handle special loggers
 	switch fn {
@@ -89,7 +137,7 @@ func (l *Logger) SetOutput(fn tsfio.Filename) error {
 			return tserr.Op(&tserr.OpArgs{Op: "create temp file", Fn: p, Err: err})
 		}
 		// Activate logging to f
-		l.logger.SetOutput(f)
+		l.setFile(f)
 		// Return nil
 		return nil
 	}
@@ -113,12 +161,52 @@ func (l *Logger) SetOutput(fn tsfio.Filename) error {
 	}
 
 	// Set Ouptut to file f
-	l.logger.SetOutput(f)
+	l.setFile(f)
 
 	// Return nil
 	return nil
 }
 
+// Close reports pending repeats of duplicate messages, pending dropped messages of the sampler
+// and pending discarded messages of the rate limiter. Then, it closes the output file or sink,
+// if any. After Close, log messages are discarded until the output is set with SetOutput. It
+// returns an error, if closing the output file or sink fails.
+func (l *Logger) Close() error {
+	// Lock the deduplication state
+	l.dedup.mu.Lock()
+	// Report pending repeats and end the current run
+	l.endRun()
+	// Unlock the deduplication state
+	l.dedup.mu.Unlock()
+	// Report pending dropped messages of the sampler, if any
+	if s := l.sampler; s != nil {
+		s.mu.Lock()
+		rep := s.flush()
+		s.mu.Unlock()
+		l.reportSampled(rep)
+	}
+	// Report pending discarded messages of the rate limiter, if any
+	if r := l.limiter; r != nil {
+		r.mu.Lock()
+		rep := r.flush()
+		r.mu.Unlock()
+		l.reportLimited(rep)
+	}
+	// Discard logging and retrieve the output file and sink
+	f, p := l.swapOutput(io.Discard, nil, nil)
+	// Close the sink, if any
+	errs := closeSink(p, nil)
+	// Close the output file, if any
+	if f != nil {
+		if err := f.Close(); err != nil {
+			// Return an error, if closing fails
+			return tserr.Op(&tserr.OpArgs{Op: "close file", Fn: f.Name(), Err: err})
+		}
+	}
+	// Return an error from closing the sink, if any
+	return errs
+}
+
 // Trace logs a message at Trace level. It returns an error if JSON encoding of msg fails.
 func (l *Logger) Trace(msg string) error {
 	return l.tryLog(TraceLevel, msg)
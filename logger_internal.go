@@ -9,6 +9,8 @@ import (
 	"fmt"           // fmt
 	"io"            // io
 	"os"            // os
+	"sort"          // sort
+	"strings"       // strings
 	"time"          // time
 
 	"github.com/thorstenrie/tserr" // tserr
@@ -18,10 +20,12 @@ import (
 // - Lvl: log level as string
 // - Msg: log message as string
 // - Now: timestamp as string
+// - Fields: structured fields, omitted if empty
 type logmsg struct {
-	Lvl string `json:"level"`   // level
-	Msg string `json:"message"` // message
-	Now string `json:"time"`    // timestamp
+	Lvl    string         `json:"level"`            // level
+	Msg    string         `json:"message"`          // message
+	Now    string         `json:"time"`             // timestamp
+	Fields map[string]any `json:"fields,omitempty"` // structured fields
 }
 
 // Struct logwrap is the JSON root element holding the log message.
@@ -31,71 +35,271 @@ type logwrap struct {
 
 // setStdout sets logging to Stdout.
 func (l *Logger) setStdout() {
-	l.logger.SetOutput(os.Stdout)
+	l.setOutput(os.Stdout, nil, nil)
 }
 
 // noLogger sets logging to discard logging.
 func (l *Logger) noLogger() {
-	l.logger.SetOutput(io.Discard)
+	l.setOutput(io.Discard, nil, nil)
+}
+
+// setFile sets logging to file f.
+func (l *Logger) setFile(f *os.File) {
+	l.setOutput(f, f, nil)
+}
+
+// setOutput sets logging to w or to sink s, if s is not nil. It closes the previous
+// sink, if any, and keeps f as output file to be closed by Close. The previous output
+// file is not closed. It returns an error, if closing the previous sink fails.
+func (l *Logger) setOutput(w io.Writer, f *os.File, s Sink) error {
+	// Set the output and retrieve the previous sink
+	_, p := l.swapOutput(w, f, s)
+	// Close the previous sink, if it is not in use anymore
+	return closeSink(p, s)
+}
+
+// swapOutput sets logging to w or to sink s, if s is not nil, and keeps f as output file.
+// It waits for entries being written to the previous output and returns the previous
+// output file and sink.
+func (l *Logger) swapOutput(w io.Writer, f *os.File, s Sink) (*os.File, Sink) {
+	// Lock the output
+	l.out.Lock()
+	// Unlock the output on return
+	defer l.out.Unlock()
+	// Set logging to w
+	l.logger.SetOutput(w)
+	// Retrieve the previous output file and sink
+	pf, ps := l.file, l.sink
+	// Keep f as output file and s as sink
+	l.file, l.sink = f, s
+	// Return the previous output file and sink
+	return pf, ps
+}
+
+// closeSink closes the previous sink p, if it is not nil and differs from the current
+// sink s. It returns an error, if closing fails.
+func closeSink(p, s Sink) error {
+	// Return nil, if there is no previous sink or it is still in use
+	if (p == nil) || (p == s) {
+		return nil
+	}
+	// Close the previous sink
+	if err := p.Close(); err != nil {
+		// Return an error, if closing fails
+		return tserr.Op(&tserr.OpArgs{Op: "close", Fn: "sink", Err: err})
+	}
+	// Return nil
+	return nil
 }
 
 // trylog logs message msg, if lvl is equal to or higher than the
-// minimum log level. It returns an error if JSON encoding of msg fails.
+// minimum log level and the message is neither dropped by a processor,
+// suppressed as a duplicate, dropped by the sampler nor discarded by the
+// rate limiter. It returns an error if a processor panics or encoding of msg fails.
 func (l *Logger) tryLog(lvl int, msg string) error {
-	// Log message if lvl is equal to or higher than the minimum log level
-	if lvl >= l.minLvl {
-		// Format log message in JSON format
-		j, e := jsonFormat(lvl, msg)
-		// Log JSON encoded log message using the logger
-		l.logger.Println(string(j))
-		// Return an error from JSON encoding, if any
-		return e
+	// Return nil, if lvl is lower than the minimum log level
+	if lvl < l.minLvl {
+		// Record the entry, if a recorder captures lvl
+		if r := l.recorder; (r != nil) && (lvl >= r.lvl) {
+			l.record(r, lvl, msg)
+		}
+		return nil
+	}
+	// Log the entry
+	return l.emit(&Entry{Level: lvl, Message: msg, Time: time.Now()})
+}
+
+// emit logs entry e regardless of the minimum log level, if it is neither dropped by a
+// processor, suppressed as a duplicate, dropped by the sampler nor discarded by the rate
+// limiter. It returns an error if a processor panics or encoding of e fails.
+func (l *Logger) emit(e *Entry) error {
+	// Execute the processors
+	ok, errp := l.process(e)
+	// Return an error of a panicking processor, if the entry is dropped
+	if !ok {
+		return errp
+	}
+	// Return an error of a panicking processor, if the entry is suppressed as a duplicate
+	if !l.deduplicate(e) {
+		return errp
+	}
+	// Sample entry, if a sampler is set
+	if s := l.sampler; s != nil {
+		// Retrieve whether the entry is logged and the dropped entries of the previous interval
+		ok, rep := s.sample(e.Level, e.Message)
+		// Report dropped entries of the previous interval, if any
+		l.reportSampled(rep)
+		// Return an error of a panicking processor, if the entry is dropped
+		if !ok {
+			return errp
+		}
+	}
+	// Limit entry rate, if a rate limiter is set
+	if r := l.limiter; r != nil {
+		// Retrieve whether the entry is logged and a notice of the rate limiter
+		ok, notice := r.allow(e.Level)
+		// Log notice, if any
+		if notice != "" {
+			l.notice(e.Level, notice)
+		}
+		// Return an error of a panicking processor, if the entry is discarded
+		if !ok {
+			return errp
+		}
+	}
+	// Log entry
+	if err := l.log(e); err != nil {
+		return err
+	}
+	// Return an error of a panicking processor, if any
+	return errp
+}
+
+// notice logs message msg at level lvl created by the logger itself, e.g. to report
+// dropped entries. The processors are executed, but duplicates, sampling and
+// rate limiting are not applied.
+func (l *Logger) notice(lvl int, msg string) {
+	// Create the log entry
+	e := &Entry{Level: lvl, Message: msg, Time: time.Now()}
+	// Execute the processors with the configuration locked for reading
+	l.out.RLock()
+	ok, _ := l.process(e)
+	l.out.RUnlock()
+	// Log the entry, if it is not dropped by a processor
+	if ok {
+		l.log(e)
+	}
+}
+
+// log redacts and truncates entry e, encodes it in the format or with the encoder of the logger and logs it.
+// If a recorder is set, e is recorded and the recorded entries are dumped before e, if requested.
+// It returns an error if encoding of e fails. The output and the configuration of encoding are
+// locked for reading.
+func (l *Logger) log(e *Entry) error {
+	// Lock the output and the configuration of encoding for reading
+	l.out.RLock()
+	// Unlock the output and the configuration of encoding on return
+	defer l.out.RUnlock()
+	// Encode entry
+	j, err := l.encode(e)
+	// Record entry and dump the recorded entries, which were not logged, if a recorder is set
+	if r := l.recorder; (r != nil) && (err == nil) {
+		for _, d := range r.record(e, j, true) {
+			l.write(&d.e, d.line, nil)
+		}
+	}
+	// Write entry
+	return l.write(e, j, err)
+}
+
+// encode redacts and truncates entry e and encodes it in the format or with the encoder of
+// the logger. It returns an error if encoding of e fails.
+func (l *Logger) encode(e *Entry) ([]byte, error) {
+	// Redact entry, if a redactor is set
+	if r := l.redactor; r != nil {
+		r.redact(e)
+	}
+	// Truncate entry according to the size limits
+	l.limits.truncate(e)
+	// Retrieve the encoder of the format or the encoder of the logger, if set
+	enc := formats[l.format]
+	if l.encoder != nil {
+		enc = l.encoder
+	}
+	// Encode log entry within the line limit
+	return l.limits.encode(enc, e)
+}
+
+// write writes entry e encoded as j to the sink, if set, or the output of the logger. If
+// encoding failed with err, it returns err without writing. It returns an error from the
+// sink, if any. The output must be locked for reading.
+func (l *Logger) write(e *Entry, j []byte, err error) error {
+	// Return an error from encoding, if any
+	if err != nil {
+		return err
 	}
+	// Log entry to the sink, if set
+	if s := l.sink; s != nil {
+		// Return an error from the sink, if any
+		return s.Log(e, j)
+	}
+	// Log encoded log entry using the logger
+	l.logger.Println(string(j))
 	// Return nil
 	return nil
 }
 
-// jsonFormat encodes lvl and msg into a JSON log message. It returns the
+// jsonFormat encodes entry e into a JSON log message. It returns the
 // JSON encoded log message or an error, if any. If JSON encoding fails,
 // it returns nil and an error.
-func jsonFormat(lvl int, msg string) ([]byte, error) {
-	// Retrieve string representation for log level lvl
-	ls, errl := level(lvl)
+func jsonFormat(e *Entry) ([]byte, error) {
+	// Retrieve string representation for log level
+	ls, errl := level(e.Level)
 	// Return nil and an error for invalid log levels
 	if errl != nil {
 		return nil, errl
 	}
 	// data holds the log message
-	data := logmsg{Lvl: ls, Msg: msg, Now: time.Now().Format(timeLayout)}
+	data := logmsg{Lvl: ls, Msg: e.Message, Now: e.Time.Format(timeLayout), Fields: e.Fields}
 	// wrap holds the log message and the JSON root element
 	wrap := logwrap{L: data}
 	// Retrieve the JSON encoding of wrap
 	j, errj := json.Marshal(&wrap)
 	// Return nil and an error, if JSON encoding fails
 	if errj != nil {
-		return nil, tserr.Op(&tserr.OpArgs{Op: "JSON Marshal", Fn: msg, Err: errj})
+		return nil, tserr.Op(&tserr.OpArgs{Op: "JSON Marshal", Fn: e.Message, Err: errj})
 	}
 	// Return the JSON encoded log message and nil
 	return j, nil
 }
 
+// levels holds the string representation of each log level from Trace level to
+// Fatal level, indexed by the log level.
+var levels = [...]string{
+	TraceLevel: "trace",
+	DebugLevel: "debug",
+	InfoLevel:  "info",
+	WarnLevel:  "warn",
+	ErrorLevel: "error",
+	FatalLevel: "fatal",
+}
+
+// formats holds the encoder of each format.
+var formats = map[Format]Encoder{
+	JSONFormat: jsonFormat,
+	GELFFormat: gelfFormat,
+	OTelFormat: NewOTelEncoder(nil),
+	ECSFormat:  NewECSEncoder(nil),
+	GCPFormat:  NewGCPEncoder(&GCPArgs{Source: true}),
+	AWSFormat:  func(e *Entry) ([]byte, error) { return awsFormat(e, &AWSArgs{}) },
+}
+
 // level returns the string representation of lvl. It returns "error" and an error,
 // if lvl is non existent.
 func level(lvl int) (string, error) {
-	switch lvl {
-	case TraceLevel:
-		return "trace", nil
-	case DebugLevel:
-		return "debug", nil
-	case InfoLevel:
-		return "info", nil
-	case WarnLevel:
-		return "warn", nil
-	case ErrorLevel:
-		return "error", nil
-	case FatalLevel:
-		return "fatal", nil
-	default:
-		return "error", tserr.NotExistent(fmt.Sprintf("log level %d", lvl))
+	// Return "error" and an error, if lvl is not in the level table
+	if (lvl < TraceLevel) || (lvl > FatalLevel) {
+		return levels[ErrorLevel], tserr.NotExistent(fmt.Sprintf("log level %d", lvl))
+	}
+	// Return the string representation of lvl
+	return levels[lvl], nil
+}
+
+// levelNames returns a comma separated list of all log levels in the level table.
+func levelNames() string {
+	return strings.Join(levels[TraceLevel:], ", ")
+}
+
+// formatNames returns a sorted and comma separated list of all formats in the format table.
+func formatNames() string {
+	// n holds the names of all formats
+	n := make([]string, 0, len(formats))
+	// Append the name of each format
+	for f := range formats {
+		n = append(n, string(f))
 	}
+	// Sort the names
+	sort.Strings(n)
+	// Return the comma separated list
+	return strings.Join(n, ", ")
 }
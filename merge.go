@@ -0,0 +1,153 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"container/heap" // heap
+	"errors"         // errors
+	"io"             // io
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// MergeSource is a named input of log messages in JSON format, e.g. a log file of a service.
+type MergeSource struct {
+	Name string    // name of the source, e.g. the filename
+	R    io.Reader // input
+}
+
+// Merged is an entry read by a Merger tagged with its source.
+type Merged struct {
+	Entry  *Entry // entry
+	Line   []byte // log message of the entry without surrounding white space
+	Source string // name of the source
+}
+
+// mergeHead is the next entry of a source of a Merger.
+type mergeHead struct {
+	m   Merged // next entry
+	src int    // index of the source
+}
+
+// mergeHeap is a min-heap of the next entries of the sources ordered by timestamp and
+// the index of the source for equal timestamps.
+type mergeHeap []mergeHead
+
+// Merger merges log messages in JSON format of multiple sources chronologically. Each
+// source must be ordered by timestamp.
+type Merger struct {
+	srcs   []MergeSource // sources
+	rs     []*Reader     // readers of the sources
+	h      mergeHeap     // next entries of the sources
+	errs   []error       // pending errors of malformed lines
+	primed bool          // true, if the first entries of the sources are read
+}
+
+// NewMerger creates a new Merger of sources srcs.
+func NewMerger(srcs ...MergeSource) *Merger {
+	// Create a reader for each source
+	rs := make([]*Reader, len(srcs))
+	for i := range srcs {
+		rs[i] = NewReader(srcs[i].R)
+	}
+	// Return the merger
+	return &Merger{srcs: srcs, rs: rs, h: make(mergeHeap, 0, len(srcs))}
+}
+
+// Read returns the next entry of the sources by timestamp. Timestamps in different time zones
+// are compared as instants. Entries with equal timestamps are returned in the order of the
+// sources and of their lines. If a line is malformed, it returns nil and an error with the
+// name of the source and the line number, and the next call continues. If reading a source
+// fails, the error is returned once and the source is skipped. At the end of all sources, it
+// returns nil and io.EOF.
+func (m *Merger) Read() (*Merged, error) {
+	// Read the first entry of each source, if not done yet
+	if !m.primed {
+		for i := range m.rs {
+			m.next(i)
+		}
+		m.primed = true
+	}
+	// Return a pending error, if any
+	if len(m.errs) > 0 {
+		err := m.errs[0]
+		m.errs = m.errs[1:]
+		return nil, err
+	}
+	// Return io.EOF at the end of all sources
+	if len(m.h) == 0 {
+		return nil, io.EOF
+	}
+	// Retrieve the earliest entry and read the next entry of its source
+	hd := heap.Pop(&m.h).(mergeHead)
+	m.next(hd.src)
+	// Return the earliest entry
+	return &hd.m, nil
+}
+
+// next reads the next entry of source i and pushes it to the heap. Errors are kept as pending
+// errors. A source, which fails to read, is skipped.
+func (m *Merger) next(i int) {
+	// Retrieve the reader and the name of source i
+	r, name := m.rs[i], m.srcs[i].Name
+	for {
+		// Read the next entry
+		e, err := r.Read()
+		// Return at the end of the source
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		// Keep the error and continue with the next line, if a line is malformed
+		if err != nil {
+			m.errs = append(m.errs, tserr.Op(&tserr.OpArgs{Op: "merge", Fn: name, Err: err}))
+			// Skip the source, if reading fails
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				return
+			}
+			continue
+		}
+		// Push the entry
+		heap.Push(&m.h, mergeHead{m: Merged{Entry: e, Line: r.Bytes(), Source: name}, src: i})
+		return
+	}
+}
+
+// Len returns the number of entries in the heap.
+func (h mergeHeap) Len() int {
+	return len(h)
+}
+
+// Less returns true, if entry i is earlier than entry j or if they are equal and the source of
+// i precedes the source of j.
+func (h mergeHeap) Less(i, j int) bool {
+	// Compare the timestamps
+	if ti, tj := h[i].m.Entry.Time, h[j].m.Entry.Time; !ti.Equal(tj) {
+		return ti.Before(tj)
+	}
+	// Compare the sources for equal timestamps
+	return h[i].src < h[j].src
+}
+
+// Swap swaps entries i and j.
+func (h mergeHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+// Push appends entry x.
+func (h *mergeHeap) Push(x any) {
+	*h = append(*h, x.(mergeHead))
+}
+
+// Pop removes and returns the last entry.
+func (h *mergeHeap) Pop() any {
+	// Retrieve the last entry
+	old := *h
+	x := old[len(old)-1]
+	// Remove the last entry
+	*h = old[:len(old)-1]
+	// Return the last entry
+	return x
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"errors"  // errors
+	"fmt"     // fmt
+	"io"      // io
+	"strings" // strings
+	"testing" // testing
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// TestMerger merges two sources in different time zones with equal timestamps and a malformed
+// line. The test fails if the entries are not ordered by timestamp, entries with equal timestamps
+// are not ordered by source, an entry is not tagged with its source or the malformed line is not
+// reported with its source and line number.
+func TestMerger(t *testing.T) {
+	// Create the sources a in UTC and b in UTC+1 with equal timestamps
+	a := testMergeInput(
+		"a1 2023-01-02 15:00:00 +0000 UTC",
+		"a2 2023-01-02 15:00:02 +0000 UTC",
+		"a3 2023-01-02 15:00:02 +0000 UTC",
+	)
+	b := testMergeInput(
+		"b1 2023-01-02 16:00:01 +0100 CET",
+		"b2 2023-01-02 16:00:02 +0100 CET",
+	) + "malformed\n" + testMergeInput("b3 2023-01-02 16:00:03 +0100 CET")
+	// Merge the sources
+	m := NewMerger(MergeSource{Name: "a", R: strings.NewReader(a)}, MergeSource{Name: "b", R: strings.NewReader(b)})
+	// got holds the merged entries and errors
+	var got []string
+	for {
+		e, err := m.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			got = append(got, err.Error())
+			continue
+		}
+		// Record an error, if the entry is not tagged with its source
+		if e.Source != e.Entry.Message[:1] {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: e.Entry.Message[:1], Y: e.Source}))
+		}
+		got = append(got, e.Entry.Message)
+	}
+	// Record an error, if the malformed line is not reported with its source and line number
+	if (len(got) != 7) || !strings.Contains(got[5], "merge b") || !strings.Contains(got[5], "line 3") {
+		t.Fatal(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "error line 3", Y: fmt.Sprint(got)}))
+	}
+	// Record an error, if the entries are not merged in order
+	got = append(got[:5], got[6:]...)
+	if s := strings.Join(got, " "); s != "a1 b1 a2 a3 b2 b3" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "a1 b1 a2 a3 b2 b3", Y: s}))
+	}
+}
+
+// testMergeInput returns log messages in JSON format for each message and timestamp in ms
+// separated by the first space.
+func testMergeInput(ms ...string) string {
+	// b holds the log messages
+	var b strings.Builder
+	// Append a log message for each message and timestamp
+	for _, m := range ms {
+		msg, ts, _ := strings.Cut(m, " ")
+		fmt.Fprintf(&b, `{"log":{"level":"info","message":"%s","time":"%s"}}`+"\n", msg, ts)
+	}
+	// Return the log messages
+	return b.String()
+}
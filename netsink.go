@@ -0,0 +1,453 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages, tserr and tsfio.
+import (
+	"errors"      // errors
+	"fmt"         // fmt
+	"net"         // net
+	"os"          // os
+	"strings"     // strings
+	"sync"        // sync
+	"sync/atomic" // atomic
+	"syscall"     // syscall
+	"time"        // time
+
+	"github.com/thorstenrie/tserr" // tserr
+	"github.com/thorstenrie/tsfio" // tsfio
+)
+
+// Defaults for network sinks
+const (
+	// Default number of buffered entries during outages
+	defaultNetBuffer int = 1000
+	// Timeout for connecting and for writing a line
+	netTimeout time.Duration = time.Second
+	// Interval between reconnect attempts
+	netRetry time.Duration = time.Second
+	// Maximum payload of a UDP datagram
+	netDatagram int = 65507
+)
+
+// NetSink is a Sink streaming entries encoded in the format of the logger as lines over
+// TCP, UDP or a unix socket. With UDP, each line is sent as a datagram. Lines are buffered
+// and sent in the background, so logging does not block on the network. During outages,
+// lines are buffered in memory up to a maximum number of lines. If the buffer is full, the
+// oldest line is dropped. The connection is re-established in the background after a retry
+// interval and the buffered lines are sent in order. If writing a line times out, only its
+// unwritten bytes are written on retry. Lines, which exceed the maximum datagram size, are
+// dropped. With a spool, lines are buffered on disk instead.
+type NetSink struct {
+	mu      sync.Mutex    // mutex for the connection, buffer and spool
+	network string        // network, e.g. "tcp" or "udp"
+	address string        // address
+	conn    net.Conn      // connection, nil during outages
+	buf     [][]byte      // buffered lines
+	head    uint64        // number of lines removed from the buffer
+	max     int           // maximum number of buffered lines
+	retry   time.Duration // interval between reconnect attempts
+	timeout time.Duration // timeout for writing a line
+	spool   *spool        // disk-backed buffer, nil for the in-memory buffer
+	rest    []byte        // unwritten bytes of a line, which timed out on connection rc
+	rc      net.Conn      // connection of rest
+	gen     uint64        // generation of the line of rest
+	err     error         // first error of the spool in the background
+	closed  bool          // true, if the sink is closed
+	wake    chan struct{} // signals buffered lines to the sender
+	quit    chan struct{} // stops the sender
+	done    chan struct{} // closed, if the sender returned
+	dropped atomic.Uint64 // number of dropped lines
+}
+
+// NewNetSink creates a new NetSink for network and address buffering up to buffer lines
+// during outages. Networks are "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6" and "unix". It returns
+// an error, if the network is not supported, buffer is not positive or connecting fails.
+func NewNetSink(network, address string, buffer int) (*NetSink, error) {
+	// Create the sink
+	s, err := newNetSink(network, address, buffer)
+	if err != nil {
+		return nil, err
+	}
+	// Connect
+	if _, err := s.dial(); err != nil {
+		return nil, err
+	}
+	// Start the sender and return the sink
+	s.start()
+	return s, nil
+}
+
+// NewSpoolNetSink creates a new NetSink for network and address buffering lines in segment
+// files in spool directory dir up to max bytes. If the spool exceeds max bytes, the oldest
+// segment is dropped. Lines remaining in the spool on Close or after a crash are sent after
+// the next start with the same directory. Up to 100 lines may be sent twice after a crash.
+// Connecting is retried, if the address is unreachable. It returns an error, if the network is not
+// supported, max is not positive or the spool directory cannot be read.
+func NewSpoolNetSink(network, address string, dir tsfio.Directory, max int64) (*NetSink, error) {
+	// Create the sink
+	s, err := newNetSink(network, address, 1)
+	if err != nil {
+		return nil, err
+	}
+	// Open the spool
+	if s.spool, err = newSpool(dir, max, &s.dropped); err != nil {
+		return nil, err
+	}
+	// Connect and leave reconnecting to the sender, if connecting fails
+	s.dial()
+	// Start the sender and return the sink
+	s.start()
+	return s, nil
+}
+
+// newNetSink returns a new unconnected NetSink for network and address buffering up to
+// buffer lines. It returns an error, if the network is not supported or buffer is not positive.
+func newNetSink(network, address string, buffer int) (*NetSink, error) {
+	// Return an error, if the network is not supported
+	switch network {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6", "unix":
+	default:
+		return nil, tserr.NotExistent(fmt.Sprintf("network %s", network))
+	}
+	// Return an error, if buffer is not positive
+	if buffer < 1 {
+		return nil, tserr.NotExistent(fmt.Sprintf("buffer size %d", buffer))
+	}
+	// Return the sink
+	return &NetSink{
+		network: network,
+		address: address,
+		max:     buffer,
+		retry:   netRetry,
+		timeout: netTimeout,
+		wake:    make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}, nil
+}
+
+// SetNetOutput sets the logging output to a NetSink for network and address with the
+// default buffer of 1000 lines. If SetNetOutput returns an error, logging is set to Stdout.
+func (l *Logger) SetNetOutput(network, address string) error {
+	// Create the network sink
+	s, err := NewNetSink(network, address, defaultNetBuffer)
+	// Set logging to Stdout and return an error, if creating the sink fails
+	if err != nil {
+		l.setStdout()
+		return err
+	}
+	// Set the network sink
+	return l.SetSink(s)
+}
+
+// Dropped returns the number of lines dropped by s, because the buffer or spool was full,
+// s was closed or a line exceeded the maximum datagram size.
+func (s *NetSink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Log buffers line to be sent in the background. It does not wait for the network. It returns
+// nil, since outages are handled by buffering. With a spool, it returns an error, if writing
+// to the spool fails.
+func (s *NetSink) Log(_ *Entry, line []byte) error {
+	// Buffer line with newline
+	err := s.buffer(append(append(make([]byte, 0, len(line)+1), line...), '\n'))
+	// Signal the sender without blocking
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	// Return the error of the spool, if any
+	return err
+}
+
+// Close stops the sender, sends the buffered lines with an immediate reconnect attempt and
+// closes the connection. It returns an error, if lines remain unsent, the spool failed or
+// closing fails. With a spool, unsent lines remain in the spool without an error.
+func (s *NetSink) Close() error {
+	// Return nil, if s is already closed
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	// Stop logging to s
+	s.closed = true
+	s.mu.Unlock()
+	// Stop the sender and wait for it
+	close(s.quit)
+	<-s.done
+	// Send the buffered lines
+	s.flush()
+	// Lock the connection, buffer and spool
+	s.mu.Lock()
+	// Unlock the connection, buffer and spool on return
+	defer s.mu.Unlock()
+	// n holds the number of unsent lines
+	n := len(s.buf)
+	s.buf = nil
+	// errs holds the first error of the spool and of closing the connection
+	errs := s.err
+	// Close the spool, if any
+	if s.spool != nil {
+		if err := s.spool.close(); errs == nil {
+			errs = err
+		}
+	}
+	// Close the connection, if connected
+	if s.conn != nil {
+		err := s.conn.Close()
+		s.conn = nil
+		// Keep an error, if closing fails
+		if (err != nil) && (errs == nil) {
+			errs = tserr.Op(&tserr.OpArgs{Op: "close connection", Fn: s.address, Err: err})
+		}
+	}
+	// Return an error, if closing fails
+	if errs != nil {
+		return errs
+	}
+	// Return an error, if lines remain unsent
+	if n > 0 {
+		return tserr.Op(&tserr.OpArgs{Op: "send", Fn: s.address, Err: fmt.Errorf("%d entries unsent", n)})
+	}
+	// Return nil
+	return nil
+}
+
+// buffer appends line b to the spool, if any, or the buffer. If the buffer is full, the
+// oldest line is dropped. If s is closed, b is dropped. It returns an error, if writing
+// to the spool fails.
+func (s *NetSink) buffer(b []byte) error {
+	// Lock the connection, buffer and spool
+	s.mu.Lock()
+	// Unlock the connection, buffer and spool on return
+	defer s.mu.Unlock()
+	// Drop b, if s is closed
+	if s.closed {
+		s.dropped.Add(1)
+		return nil
+	}
+	// Append b to the spool, if any
+	if s.spool != nil {
+		return s.spool.append(b)
+	}
+	// Drop the oldest line, if the buffer is full
+	if len(s.buf) >= s.max {
+		s.buf = s.buf[1:]
+		s.head++
+		s.dropped.Add(1)
+	}
+	// Append b to the buffer
+	s.buf = append(s.buf, b)
+	// Return nil
+	return nil
+}
+
+// start starts the sender in the background.
+func (s *NetSink) start() {
+	go s.run()
+}
+
+// run sends the buffered lines in the background, until the sender is stopped. It waits for
+// new lines, if all lines are sent, and for the retry interval, if sending fails.
+func (s *NetSink) run() {
+	// Signal the return of the sender
+	defer close(s.done)
+	for {
+		// Wait for new lines and return, if the sender is stopped
+		if s.flush() {
+			select {
+			case <-s.quit:
+				return
+			case <-s.wake:
+			}
+			continue
+		}
+		// Wait for the retry interval and return, if the sender is stopped
+		t := time.NewTimer(s.retry)
+		select {
+		case <-s.quit:
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// flush connects, if not connected and lines are buffered, and sends the buffered lines in
+// order. It returns false, if connecting, reading the spool or sending fails.
+func (s *NetSink) flush() bool {
+	for {
+		// Retrieve the unwritten bytes of a line, if any
+		b, gen := s.rest, s.gen
+		// Return true, if all lines are sent
+		if (b == nil) && s.empty() {
+			return true
+		}
+		// Retrieve the connection and connect, if not connected
+		c, err := s.connection()
+		if err != nil {
+			return false
+		}
+		// Send the line entirely, if the unwritten bytes belong to a previous connection
+		if (b != nil) && (c != s.rc) {
+			s.rest = nil
+			continue
+		}
+		// Retrieve the next line after connecting, so the spool is only read, if connected
+		if b == nil {
+			// Return false, if reading the spool fails
+			if b, gen, err = s.peek(); err != nil {
+				return false
+			}
+			// Return true, if all lines are sent
+			if b == nil {
+				return true
+			}
+		}
+		// Return false, if writing fails
+		if !s.write(c, b) {
+			s.gen = gen
+			return false
+		}
+		// Remove the sent line
+		s.pop(gen)
+	}
+}
+
+// empty returns true, if no line is buffered.
+func (s *NetSink) empty() bool {
+	// Lock the connection, buffer and spool
+	s.mu.Lock()
+	// Unlock the connection, buffer and spool on return
+	defer s.mu.Unlock()
+	// Return true, if the spool holds no unread lines
+	if s.spool != nil {
+		return s.spool.empty()
+	}
+	// Return true, if the buffer is empty
+	return len(s.buf) == 0
+}
+
+// peek returns the oldest buffered line and its generation. The line is nil, if no line is
+// buffered. It returns an error, if reading the spool fails.
+func (s *NetSink) peek() ([]byte, uint64, error) {
+	// Lock the connection, buffer and spool
+	s.mu.Lock()
+	// Unlock the connection, buffer and spool on return
+	defer s.mu.Unlock()
+	// Return the oldest line of the spool, if any
+	if s.spool != nil {
+		b, err := s.spool.peek()
+		// Keep the first error, if reading the spool fails
+		if (err != nil) && (s.err == nil) {
+			s.err = err
+		}
+		return b, s.spool.gen, err
+	}
+	// Return nil, if the buffer is empty
+	if len(s.buf) == 0 {
+		return nil, 0, nil
+	}
+	// Return the oldest line of the buffer
+	return s.buf[0], s.head, nil
+}
+
+// pop removes the sent line of generation gen. The line is not removed, if it was dropped
+// meanwhile.
+func (s *NetSink) pop(gen uint64) {
+	// Lock the connection, buffer and spool
+	s.mu.Lock()
+	// Unlock the connection, buffer and spool on return
+	defer s.mu.Unlock()
+	// Remove the line from the spool, if any
+	if s.spool != nil {
+		if s.spool.gen == gen {
+			s.spool.next()
+		}
+		return
+	}
+	// Remove the line from the buffer, if it was not dropped
+	if (len(s.buf) > 0) && (s.head == gen) {
+		s.buf = s.buf[1:]
+		s.head++
+	}
+}
+
+// connection returns the connection and connects, if not connected. It returns an error,
+// if connecting fails.
+func (s *NetSink) connection() (net.Conn, error) {
+	// Retrieve the connection
+	s.mu.Lock()
+	c := s.conn
+	s.mu.Unlock()
+	// Return the connection, if connected
+	if c != nil {
+		return c, nil
+	}
+	// Connect
+	return s.dial()
+}
+
+// dial connects to the address without holding the lock. It returns the connection or
+// an error, if connecting fails.
+func (s *NetSink) dial() (net.Conn, error) {
+	// Connect to the address
+	c, err := net.DialTimeout(s.network, s.address, netTimeout)
+	// Return an error, if connecting fails
+	if err != nil {
+		return nil, tserr.Op(&tserr.OpArgs{Op: "connect", Fn: s.address, Err: err})
+	}
+	// Keep the connection
+	s.mu.Lock()
+	s.conn = c
+	s.mu.Unlock()
+	// Return the connection
+	return c, nil
+}
+
+// write writes line b to connection c with the write timeout. If writing times out, the
+// unwritten bytes of b are kept to be written first on retry on the same connection. If
+// writing fails otherwise, the connection is closed and the line is sent entirely on the next
+// connection. If b exceeds the maximum datagram size, it is dropped. It returns true, if b is
+// written or dropped.
+func (s *NetSink) write(c net.Conn, b []byte) bool {
+	// Drop b and return true, if it exceeds the maximum payload of a UDP datagram
+	if strings.HasPrefix(s.network, "udp") && (len(b) > netDatagram) {
+		s.rest, s.rc = nil, nil
+		s.dropped.Add(1)
+		return true
+	}
+	// Write b with the write timeout
+	c.SetWriteDeadline(time.Now().Add(s.timeout))
+	n, err := c.Write(b)
+	// Return true, if b is written
+	if err == nil {
+		s.rest, s.rc = nil, nil
+		return true
+	}
+	// Keep the unwritten bytes of b and return false, if writing timed out
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		s.rest, s.rc = b[n:], c
+		return false
+	}
+	// Drop b and return true, if it exceeds the maximum datagram size
+	if errors.Is(err, syscall.EMSGSIZE) {
+		s.rest, s.rc = nil, nil
+		s.dropped.Add(1)
+		return true
+	}
+	// Close the failed connection
+	s.rest, s.rc = nil, nil
+	s.mu.Lock()
+	if s.conn == c {
+		s.conn = nil
+	}
+	s.mu.Unlock()
+	c.Close()
+	// Return false
+	return false
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"bufio"   // bufio
+	"io"      // io
+	"net"     // net
+	"strings" // strings
+	"testing" // testing
+	"time"    // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// TestNetSinkTCP logs entries to a local TCP listener, simulates an outage and logs further entries.
+// The test fails if the received lines do not match the logged entries in order.
+func TestNetSinkTCP(t *testing.T) {
+	// Listen on a local TCP socket
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	// Stop execution, if listening fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "listen", Fn: "tcp", Err: err}))
+	}
+	// Close the listener on return
+	defer ln.Close()
+	// Create network sink s, which reconnects immediately after an outage
+	s, err := newNetSink("tcp", ln.Addr().String(), defaultNetBuffer)
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New net sink", Fn: ln.Addr().String(), Err: err}))
+	}
+	s.retry = 0
+	if _, err := s.dial(); err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "connect", Fn: ln.Addr().String(), Err: err}))
+	}
+	s.start()
+	// Create new logger lg with output to s
+	lg := New()
+	if err := lg.SetSink(s); err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "Set sink", Fn: ln.Addr().String(), Err: err}))
+	}
+	// Log an entry and receive it with the first connection
+	testLogger(&testcase{level: InfoLevel, in: "a"}, lg)
+	testNetReceive(t, ln, []*testcase{{InfoLevel, "a"}})
+	// Simulate an outage by closing the connection of the sink
+	s.mu.Lock()
+	s.conn.Close()
+	s.mu.Unlock()
+	// Log entries, which are sent after reconnecting
+	testLogger(&testcase{level: WarnLevel, in: "b"}, lg)
+	testLogger(&testcase{level: ErrorLevel, in: "c"}, lg)
+	// Close the logger
+	if err := lg.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: ln.Addr().String(), Err: err}))
+	}
+	// Receive the entries with the second connection
+	testNetReceive(t, ln, []*testcase{{WarnLevel, "b"}, {ErrorLevel, "c"}})
+}
+
+// TestNetSinkBuffer logs entries during an outage with a buffer of two lines. The test
+// fails if the oldest lines are not dropped or if Close does not report unsent lines.
+func TestNetSinkBuffer(t *testing.T) {
+	// Listen on a local TCP socket
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	// Stop execution, if listening fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "listen", Fn: "tcp", Err: err}))
+	}
+	// Create network sink s with a buffer of two lines
+	s, err := NewNetSink("tcp", ln.Addr().String(), 2)
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New net sink", Fn: ln.Addr().String(), Err: err}))
+	}
+	// Simulate an outage by closing the listener and the connection
+	ln.Close()
+	s.mu.Lock()
+	s.conn.Close()
+	s.mu.Unlock()
+	// Log four lines
+	for i := 0; i < 4; i++ {
+		if err := s.Log(nil, []byte("test")); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Log", Fn: ln.Addr().String(), Err: err}))
+		}
+	}
+	// Record an error, if the number of dropped lines is not two
+	if d := s.Dropped(); d != 2 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "Dropped", Actual: int64(d), Want: 2}))
+	}
+	// Record an error, if Close does not report unsent lines
+	if err := s.Close(); err == nil {
+		t.Error(tserr.NilFailed("Close"))
+	}
+}
+
+// TestNetSinkStalled logs a line to a peer, which stops reading after three bytes. The test
+// fails if Log blocks or the line is not completed with its unwritten bytes after the write
+// timeout.
+func TestNetSinkStalled(t *testing.T) {
+	// Create network sink s with a short write timeout and retry interval on a pipe
+	s, err := newNetSink("tcp", "pipe", defaultNetBuffer)
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New net sink", Fn: "pipe", Err: err}))
+	}
+	s.timeout, s.retry = 20*time.Millisecond, 10*time.Millisecond
+	c, p := net.Pipe()
+	s.conn = c
+	s.start()
+	// Log a line and record an error, if Log blocks
+	start := time.Now()
+	if err := s.Log(nil, []byte("abcdef")); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Log", Fn: "pipe", Err: err}))
+	}
+	if d := time.Since(start); d > s.timeout {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "Log duration in ms", Actual: d.Milliseconds(), Want: 0}))
+	}
+	// Read three bytes and stall until the write timed out
+	b := make([]byte, 7)
+	if _, err := io.ReadFull(p, b[:3]); err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "read", Fn: "pipe", Err: err}))
+	}
+	time.Sleep(5 * s.timeout)
+	// Read the unwritten bytes
+	if _, err := io.ReadFull(p, b[3:]); err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "read", Fn: "pipe", Err: err}))
+	}
+	// Record an error, if the line is not received exactly once
+	if string(b) != "abcdef\n" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "abcdef\n", Y: string(b)}))
+	}
+	// Close the sink and the pipe
+	if err := s.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: "pipe", Err: err}))
+	}
+	p.Close()
+}
+
+// TestNetSinkUDP logs an entry to a local UDP socket. The test fails if the received
+// datagram does not match the logged entry.
+func TestNetSinkUDP(t *testing.T) {
+	// Listen on a local UDP socket
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	// Stop execution, if listening fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "listen", Fn: "udp", Err: err}))
+	}
+	// Close the socket on return
+	defer c.Close()
+	// Create new logger lg with output to the socket
+	lg := New()
+	if err := lg.SetNetOutput("udp", c.LocalAddr().String()); err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "Set net output", Fn: c.LocalAddr().String(), Err: err}))
+	}
+	// Log an entry
+	tc := &testcase{level: InfoLevel, in: "test"}
+	testLogger(tc, lg)
+	// Receive the datagram and evaluate the log message without newline
+	msg := testReceive(t, c.(*net.UDPConn))
+	testMessage(t, []byte(msg[:len(msg)-1]), tc)
+	// Close the logger
+	if err := lg.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: c.LocalAddr().String(), Err: err}))
+	}
+}
+
+// TestNetSinkDatagram logs a line exceeding the maximum datagram size followed by a small
+// line to a local UDP socket. The test fails if the small line is not received or the large
+// line is not dropped.
+func TestNetSinkDatagram(t *testing.T) {
+	// Listen on a local UDP socket
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	// Stop execution, if listening fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "listen", Fn: "udp", Err: err}))
+	}
+	// Close the socket on return
+	defer c.Close()
+	// Create network sink s
+	s, err := NewNetSink("udp", c.LocalAddr().String(), defaultNetBuffer)
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New net sink", Fn: c.LocalAddr().String(), Err: err}))
+	}
+	// Log a line exceeding the maximum datagram size and a small line
+	for _, line := range []string{strings.Repeat("x", 70000), "small"} {
+		if err := s.Log(nil, []byte(line)); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Log", Fn: c.LocalAddr().String(), Err: err}))
+		}
+	}
+	// Record an error, if the small line is not received
+	if msg := testReceive(t, c.(*net.UDPConn)); msg != "small\n" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "small\n", Y: msg}))
+	}
+	// Close the sink
+	if err := s.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: c.LocalAddr().String(), Err: err}))
+	}
+	// Record an error, if the large line is not dropped
+	if d := s.Dropped(); d != 1 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "Dropped", Actual: int64(d), Want: 1}))
+	}
+}
+
+// TestNetOutputErr sets the network output with an unsupported network. The test fails
+// if SetNetOutput does not return an error.
+func TestNetOutputErr(t *testing.T) {
+	// Record an error, if SetNetOutput returns nil
+	if err := SetNetOutput("ip", "127.0.0.1"); err == nil {
+		t.Error(tserr.NilFailed("Set net output"))
+	}
+}
+
+// testNetReceive accepts a connection of listener ln and reads the lines of want. The test
+// fails if the lines do not match want.
+func testNetReceive(t *testing.T, ln net.Listener, want []*testcase) {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Accept the connection
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "accept", Fn: "tcp", Err: err}))
+	}
+	// Close the connection on return
+	defer c.Close()
+	// Read the lines of the connection
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	fs := bufio.NewScanner(c)
+	i := 0
+	for ; (i < len(want)) && fs.Scan(); i++ {
+		testMessage(t, fs.Bytes(), want[i])
+	}
+	// Record an error, if the number of lines does not match
+	if i != len(want) {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "No. lines", Actual: int64(i), Want: int64(len(want))}))
+	}
+}
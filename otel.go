@@ -0,0 +1,223 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"bytes"         // bytes
+	"encoding/hex"  // hex
+	"encoding/json" // json
+	"fmt"           // fmt
+	"os"            // os
+	"path/filepath" // filepath
+	"sort"          // sort
+	"strconv"       // strconv
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// Field keys holding the trace context for the OpenTelemetry logs data model
+const (
+	TraceIDField string = "trace_id" // trace id as 32 hex digits
+	SpanIDField  string = "span_id"  // span id as 16 hex digits
+)
+
+// Defaults for OpenTelemetry
+const (
+	// Instrumentation scope name
+	otelScope string = "github.com/thorstenrie/tslog"
+	// Resource attribute of the service name
+	otelService string = "service.name"
+	// Content type of OTLP/JSON
+	otlpJSON string = "application/json"
+)
+
+// otelSeverities holds the OpenTelemetry severity number of each log level, indexed by the log level.
+var otelSeverities = [...]int{
+	TraceLevel: 1,  // TRACE
+	DebugLevel: 5,  // DEBUG
+	InfoLevel:  9,  // INFO
+	WarnLevel:  13, // WARN
+	ErrorLevel: 17, // ERROR
+	FatalLevel: 21, // FATAL
+}
+
+// otelAttr is an OTLP/JSON key value pair.
+type otelAttr struct {
+	Key   string         `json:"key"`   // key
+	Value map[string]any `json:"value"` // value as AnyValue
+}
+
+// otelRecord is an OTLP/JSON log record.
+type otelRecord struct {
+	Time     string         `json:"timeUnixNano"`         // timestamp
+	Observed string         `json:"observedTimeUnixNano"` // observed timestamp
+	SevNum   int            `json:"severityNumber"`       // severity number
+	SevText  string         `json:"severityText"`         // severity text
+	Body     map[string]any `json:"body"`                 // message as AnyValue
+	Attrs    []otelAttr     `json:"attributes,omitempty"` // fields
+	TraceID  string         `json:"traceId,omitempty"`    // trace id
+	SpanID   string         `json:"spanId,omitempty"`     // span id
+}
+
+// otelEncoder encodes entries in the OpenTelemetry logs data model.
+type otelEncoder struct {
+	resource []byte // OTLP/JSON encoded resource
+}
+
+// NewOTelEncoder returns an Encoder for the OpenTelemetry logs data model as OTLP/JSON.
+// Each entry is encoded as an export request with a single log record with the resource
+// attributes resource. If resource does not contain service.name, it is set to
+// unknown_service: and the program name. Fields are encoded as attributes. Fields
+// TraceIDField and SpanIDField with valid hex ids are encoded as traceId and spanId.
+// The encoded entries can be read by the OTLP JSON file receiver of a collector.
+func NewOTelEncoder(resource map[string]any) Encoder {
+	return newOTelEncoder(resource).encode
+}
+
+// NewOTLPSink creates an HTTPSink posting entries as OTLP/JSON export requests with the
+// resource attributes resource to the OTLP/HTTP logs endpoint in a, e.g.
+// http://localhost:4318/v1/logs. Each batch is posted as a single export request. It
+// returns an error, if a is nil, the URL is invalid, a value is negative or the spool
+// directory cannot be read.
+func NewOTLPSink(a *HTTPArgs, resource map[string]any) (*HTTPSink, error) {
+	// Create the encoder
+	o := newOTelEncoder(resource)
+	// Create the sink posting export requests
+	s, err := newHTTPSink(a, o.wrap, otlpJSON)
+	if err != nil {
+		return nil, err
+	}
+	// Encode entries as log records
+	s.enc = o.record
+	// Return the sink
+	return s, nil
+}
+
+// newOTelEncoder returns a new otelEncoder with resource attributes resource.
+func newOTelEncoder(resource map[string]any) *otelEncoder {
+	// Copy the resource attributes and set the default service name, if needed
+	r := make(map[string]any, len(resource)+1)
+	for k, v := range resource {
+		r[k] = v
+	}
+	if _, ok := r[otelService]; !ok {
+		r[otelService] = "unknown_service:" + filepath.Base(os.Args[0])
+	}
+	// Encode the resource, which cannot fail for attributes
+	j, _ := json.Marshal(map[string]any{"attributes": otelAttrs(r)})
+	// Return the encoder
+	return &otelEncoder{resource: j}
+}
+
+// encode encodes entry e as export request with a single log record. It returns
+// nil and an error, if the log level is invalid or JSON encoding fails.
+func (o *otelEncoder) encode(e *Entry) ([]byte, error) {
+	// Encode the log record
+	r, err := o.record(e)
+	if err != nil {
+		return nil, err
+	}
+	// Return the export request
+	return o.wrap([][]byte{r}), nil
+}
+
+// record encodes entry e as OTLP/JSON log record. It returns nil and an error, if
+// the log level is invalid or JSON encoding fails.
+func (o *otelEncoder) record(e *Entry) ([]byte, error) {
+	// Retrieve string representation for log level
+	ls, errl := level(e.Level)
+	// Return nil and an error for invalid log levels
+	if errl != nil {
+		return nil, errl
+	}
+	// Retrieve the timestamp in nanoseconds
+	ts := strconv.FormatInt(e.Time.UnixNano(), 10)
+	// r holds the log record
+	r := otelRecord{Time: ts, Observed: ts, SevNum: otelSeverities[e.Level], SevText: ls, Body: otelValue(e.Message)}
+	// f holds the fields without the trace context
+	f := make(map[string]any, len(e.Fields))
+	for k, v := range e.Fields {
+		f[k] = v
+	}
+	// Move valid trace and span ids from the fields to the log record
+	if id, ok := f[TraceIDField].(string); ok && otelID(id, 16) {
+		r.TraceID = id
+		delete(f, TraceIDField)
+	}
+	if id, ok := f[SpanIDField].(string); ok && otelID(id, 8) {
+		r.SpanID = id
+		delete(f, SpanIDField)
+	}
+	// Set the attributes
+	r.Attrs = otelAttrs(f)
+	// Retrieve the JSON encoding of r
+	j, errj := json.Marshal(&r)
+	// Return nil and an error, if JSON encoding fails
+	if errj != nil {
+		return nil, tserr.Op(&tserr.OpArgs{Op: "JSON Marshal", Fn: e.Message, Err: errj})
+	}
+	// Return the log record and nil
+	return j, nil
+}
+
+// wrap returns an export request with the log records in records.
+func (o *otelEncoder) wrap(records [][]byte) []byte {
+	// b holds the export request
+	var b bytes.Buffer
+	// Append the resource and scope
+	b.WriteString(`{"resourceLogs":[{"resource":`)
+	b.Write(o.resource)
+	b.WriteString(`,"scopeLogs":[{"scope":{"name":"` + otelScope + `"},"logRecords":[`)
+	// Append the log records separated by commas
+	b.Write(bytes.Join(records, []byte(",")))
+	// Close the export request
+	b.WriteString(`]}]}]}`)
+	// Return the export request
+	return b.Bytes()
+}
+
+// otelAttrs returns the attributes of m sorted by key.
+func otelAttrs(m map[string]any) []otelAttr {
+	// a holds the attributes
+	a := make([]otelAttr, 0, len(m))
+	// Append each attribute
+	for k, v := range m {
+		a = append(a, otelAttr{Key: k, Value: otelValue(v)})
+	}
+	// Sort the attributes by key
+	sort.Slice(a, func(i, j int) bool { return a[i].Key < a[j].Key })
+	// Return the attributes
+	return a
+}
+
+// otelValue returns v as OTLP/JSON AnyValue. Integers are encoded as strings, floats as
+// doubles, booleans as bools and other values by their string representation.
+func otelValue(v any) map[string]any {
+	// Return the AnyValue of v by its type
+	switch x := v.(type) {
+	case bool:
+		return map[string]any{"boolValue": x}
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return map[string]any{"intValue": fmt.Sprint(x)}
+	case float32, float64:
+		return map[string]any{"doubleValue": x}
+	case string:
+		return map[string]any{"stringValue": x}
+	}
+	// Return the string representation otherwise
+	return map[string]any{"stringValue": fmt.Sprint(v)}
+}
+
+// otelID returns true, if id is a non-zero hex id of n bytes.
+func otelID(id string, n int) bool {
+	// Decode id
+	b, err := hex.DecodeString(id)
+	// Return false, if id is not a hex id of n bytes
+	if (err != nil) || (len(b) != n) {
+		return false
+	}
+	// Return true, if id is not zero
+	return !bytes.Equal(b, make([]byte, n))
+}
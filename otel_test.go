@@ -0,0 +1,189 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"encoding/json"     // json
+	"io"                // io
+	"net/http"          // http
+	"net/http/httptest" // httptest
+	"strings"           // strings
+	"sync"              // sync
+	"testing"           // testing
+	"time"              // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// otlpRequest is an OTLP/JSON export request for decoding in tests.
+type otlpRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []otelAttr `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			LogRecords []otelRecord `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+// Trace context for tests
+const (
+	testTraceID string = "4bf92f3577b34da6a3ce929d0e0e4736" // trace id
+	testSpanID  string = "00f067aa0ba902b7"                 // span id
+)
+
+// TestOTelEncoder logs an entry with trace context and a field with an OpenTelemetry encoder
+// with a service name. The test fails if the log record or resource does not match.
+func TestOTelEncoder(t *testing.T) {
+	// Create new logger lg logging to the temporary file fn
+	lg, fn := traceLogger(t)
+	// Set the OpenTelemetry encoder with a service name
+	lg.SetEncoder(NewOTelEncoder(map[string]any{otelService: "test"}))
+	// Add processor setting the trace context and a field
+	lg.AddProcessor(testOTelContext)
+	// Log an entry at Warn level
+	if err := lg.Warn("test"); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Warn", Fn: string(fn), Err: err}))
+	}
+	// Retrieve the logged export request
+	fs := scanner(t, fn)
+	rm(t, fn)
+	if !fs.Scan() {
+		t.Fatal(tserr.NilFailed("Scan"))
+	}
+	// Evaluate the export request
+	testOTel(t, fs.Bytes(), "test", 1)
+}
+
+// TestOTelFormat logs an entry in the OpenTelemetry format. The test fails if the
+// resource does not contain the default service name.
+func TestOTelFormat(t *testing.T) {
+	// Create new logger lg logging to the temporary file fn
+	lg, fn := traceLogger(t)
+	// Set the OpenTelemetry format
+	if err := lg.SetFormat(OTelFormat); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "SetFormat", Fn: string(OTelFormat), Err: err}))
+	}
+	// Log an entry at Info level
+	if err := lg.Info("test"); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: string(fn), Err: err}))
+	}
+	// Retrieve the logged export request
+	fs := scanner(t, fn)
+	rm(t, fn)
+	if !fs.Scan() {
+		t.Fatal(tserr.NilFailed("Scan"))
+	}
+	// Record an error, if the default service name is missing
+	if want := `{"key":"service.name","value":{"stringValue":"unknown_service:`; !strings.Contains(fs.Text(), want) {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: want, Y: fs.Text()}))
+	}
+}
+
+// TestOTLPSink logs two entries with trace context to a test OTLP/HTTP endpoint. The test
+// fails if the entries are not posted as a single export request with two log records.
+func TestOTLPSink(t *testing.T) {
+	// body and ctype hold the last request body and content type
+	var (
+		mu    sync.Mutex
+		body  []byte
+		ctype string
+	)
+	// Create the test endpoint
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ = io.ReadAll(r.Body)
+		ctype = r.Header.Get("Content-Type")
+	}))
+	defer srv.Close()
+	// Create the OTLP sink with a service name
+	s, err := NewOTLPSink(&HTTPArgs{URL: srv.URL, Interval: time.Hour}, map[string]any{otelService: "test"})
+	// Stop execution, if creating the sink fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New OTLP sink", Fn: srv.URL, Err: err}))
+	}
+	// Create new logger lg with sink s
+	lg := New()
+	if err := lg.SetSink(s); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set sink", Fn: srv.URL, Err: err}))
+	}
+	// Add processor setting the trace context and a field
+	lg.AddProcessor(testOTelContext)
+	// Log two entries at Warn level
+	for i := 0; i < 2; i++ {
+		if err := lg.Warn("test"); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Warn", Fn: srv.URL, Err: err}))
+		}
+	}
+	// Close the logger, which posts the entries
+	if err := lg.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: srv.URL, Err: err}))
+	}
+	// Lock the request
+	mu.Lock()
+	defer mu.Unlock()
+	// Record an error, if the content type is not JSON
+	if ctype != otlpJSON {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: otlpJSON, Y: ctype}))
+	}
+	// Evaluate the export request
+	testOTel(t, body, "test", 2)
+}
+
+// testOTelContext sets the trace context and field tenant of entry e.
+func testOTelContext(e *Entry) bool {
+	e.SetField(TraceIDField, testTraceID)
+	e.SetField(SpanIDField, testSpanID)
+	e.SetField("tenant", "a")
+	return true
+}
+
+// testOTel decodes export request j and records an error, if the service name is not service, it
+// does not contain n log records or a log record does not match a Warn entry with trace context.
+func testOTel(t *testing.T, j []byte, service string, n int) {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Decode the export request
+	var r otlpRequest
+	if err := json.Unmarshal(j, &r); err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "JSON Unmarshal", Fn: string(j), Err: err}))
+	}
+	// Stop execution, if the export request does not contain a single resource and scope
+	if (len(r.ResourceLogs) != 1) || (len(r.ResourceLogs[0].ScopeLogs) != 1) {
+		t.Fatal(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "single resource and scope", Y: string(j)}))
+	}
+	// Record an error, if the service name does not match
+	if a := r.ResourceLogs[0].Resource.Attributes; (len(a) != 1) || (a[0].Value["stringValue"] != service) {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: service, Y: string(j)}))
+	}
+	// Stop execution, if the number of log records does not match
+	recs := r.ResourceLogs[0].ScopeLogs[0].LogRecords
+	if len(recs) != n {
+		t.Fatal(tserr.Equal(&tserr.EqualArgs{Var: "log records", Actual: int64(len(recs)), Want: int64(n)}))
+	}
+	// Iterate the log records
+	for _, rec := range recs {
+		// Record an error, if the severity does not match
+		if (rec.SevNum != 13) || (rec.SevText != "warn") {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "13 warn", Y: string(j)}))
+		}
+		// Record an error, if body or trace context do not match
+		if (rec.Body["stringValue"] != "test") || (rec.TraceID != testTraceID) || (rec.SpanID != testSpanID) {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "test " + testTraceID + " " + testSpanID, Y: string(j)}))
+		}
+		// Record an error, if the attributes do not contain only the field tenant
+		if (len(rec.Attrs) != 1) || (rec.Attrs[0].Key != "tenant") {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "tenant", Y: string(j)}))
+		}
+		// Record an error, if the timestamp is missing
+		if rec.Time == "" {
+			t.Error(tserr.NilFailed("timeUnixNano"))
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"fmt"         // fmt
+	"sync"        // sync
+	"sync/atomic" // atomic
+	"time"        // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// RateLimiter limits the number of log messages per level with a token bucket for
+// each level. Levels without a limit are never limited. When limiting kicks in for a
+// level, a log message is logged at the level. When messages are logged again or the logger
+// is closed, the number of discarded messages is logged. A RateLimiter is set on a logger
+// with SetRateLimiter.
+type RateLimiter struct {
+	mu      sync.Mutex                    // mutex for the token buckets
+	buckets [FatalLevel + 1]*bucket       // token bucket per level, nil for unlimited levels
+	total   [FatalLevel + 1]atomic.Uint64 // total number of discarded messages per level
+	now     func() time.Time              // clock
+}
+
+// bucket is a token bucket for one level.
+type bucket struct {
+	rate     float64   // tokens added per second
+	burst    float64   // maximum number of tokens
+	tokens   float64   // available tokens
+	last     time.Time // time of the last update of tokens
+	dropped  uint64    // number of discarded messages since limiting kicked in
+	limiting bool      // true, if limiting is active
+}
+
+// NewRateLimiter creates a new RateLimiter without limits. Limits are set per level with SetLimit.
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{now: time.Now}
+}
+
+// SetLimit limits messages at level lvl to rate messages per second with bursts of up
+// to burst messages. If rate is zero, the limit for lvl is removed. SetLimit returns an
+// error for undefined levels, a negative rate or a burst lower than one.
+func (r *RateLimiter) SetLimit(lvl int, rate float64, burst int) error {
+	// Return an error for undefined levels
+	if _, err := level(lvl); err != nil {
+		return err
+	}
+	// Return an error for a negative rate
+	if rate < 0 {
+		return tserr.NotExistent(fmt.Sprintf("rate %v", rate))
+	}
+	// Lock the token buckets
+	r.mu.Lock()
+	// Unlock the token buckets on return
+	defer r.mu.Unlock()
+	// Remove the limit, if rate is zero
+	if rate == 0 {
+		r.buckets[lvl] = nil
+		// Return nil
+		return nil
+	}
+	// Return an error for a burst lower than one
+	if burst < 1 {
+		return tserr.NotExistent(fmt.Sprintf("burst %d", burst))
+	}
+	// Set a full token bucket for lvl
+	r.buckets[lvl] = &bucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: r.now()}
+	// Return nil
+	return nil
+}
+
+// Dropped returns the total number of messages at level lvl discarded by r. It
+// returns zero for undefined levels.
+func (r *RateLimiter) Dropped(lvl int) uint64 {
+	// Return zero for undefined levels
+	if _, err := level(lvl); err != nil {
+		return 0
+	}
+	// Return the number of discarded messages
+	return r.total[lvl].Load()
+}
+
+// SetRateLimiter sets rate limiter r for the logger. Rate limiting applies to messages
+// which are not dropped by sampling. If r is nil, rate limiting is disabled.
+func (l *Logger) SetRateLimiter(r *RateLimiter) {
+	l.limiter = r
+}
+
+// allow takes a token for a message at level lvl. It returns true, if the message is logged
+// and false, if it is discarded. It also returns a message to be logged at level lvl before
+// the message, if limiting kicks in or ends. Otherwise, the returned message is empty.
+func (r *RateLimiter) allow(lvl int) (bool, string) {
+	// Log messages at undefined levels
+	if (lvl < TraceLevel) || (lvl > FatalLevel) {
+		return true, ""
+	}
+	// Lock the token buckets
+	r.mu.Lock()
+	// Unlock the token buckets on return
+	defer r.mu.Unlock()
+	// Retrieve the token bucket for lvl
+	b := r.buckets[lvl]
+	// Log message, if lvl is not limited
+	if b == nil {
+		return true, ""
+	}
+	// Retrieve current time
+	now := r.now()
+	// Add tokens for the elapsed time up to burst
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	// Take a token and log message, if available
+	if b.tokens >= 1 {
+		// Take a token
+		b.tokens--
+		// Log message, if limiting is not active
+		if !b.limiting {
+			return true, ""
+		}
+		// Retrieve the number of discarded messages and end limiting
+		n := b.dropped
+		b.dropped, b.limiting = 0, false
+		// Log message and report the number of discarded messages
+		return true, fmt.Sprintf("%d entries discarded by rate limit", n)
+	}
+	// Count discarded message
+	b.dropped++
+	r.total[lvl].Add(1)
+	// Discard message, if limiting is already active
+	if b.limiting {
+		return false, ""
+	}
+	// Activate limiting
+	b.limiting = true
+	// Discard message and report that limiting kicks in
+	return false, fmt.Sprintf("rate limit of %v entries per second exceeded, discarding entries", b.rate)
+}
+
+// flush ends limiting for all levels and returns the number of discarded messages per level
+// since limiting kicked in. It returns nil, if limiting is not active for any level. The token
+// buckets must be locked.
+func (r *RateLimiter) flush() []uint64 {
+	// rep holds the number of discarded messages per level
+	var rep []uint64
+	// Iterate all levels
+	for lvl, b := range r.buckets {
+		// Skip levels without active limiting
+		if (b == nil) || !b.limiting {
+			continue
+		}
+		// Allocate rep for the first level with active limiting
+		if rep == nil {
+			rep = make([]uint64, len(r.buckets))
+		}
+		// Move the number of discarded messages to rep and end limiting
+		rep[lvl], b.dropped, b.limiting = b.dropped, 0, false
+	}
+	// Return the number of discarded messages
+	return rep
+}
+
+// reportLimited logs the number of discarded messages per level in rep at the respective level.
+func (l *Logger) reportLimited(rep []uint64) {
+	// Iterate all levels
+	for lvl, n := range rep {
+		// Log the number of discarded messages, if any
+		if n > 0 {
+			l.notice(lvl, fmt.Sprintf("%d entries discarded by rate limit", n))
+		}
+	}
+}
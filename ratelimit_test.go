@@ -0,0 +1,120 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"errors"  // errors
+	"testing" // testing
+	"time"    // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// TestRateLimiter limits Info level to two messages per second and logs five messages at Info
+// level and three messages at Error level. After one second, it logs another message at Info
+// level. The test fails if the logged messages or the number of discarded messages do not
+// match the expected result.
+func TestRateLimiter(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Create new rate limiter r with a fixed clock
+	r := NewRateLimiter()
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	// Limit Info level to two messages per second
+	if err := r.SetLimit(InfoLevel, 2, 2); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set limit", Fn: "info", Err: err}))
+	}
+	// Set rate limiter r
+	lg.SetRateLimiter(r)
+	// Log five messages at Info level and three messages at Error level
+	for i := 0; i < 5; i++ {
+		if err := lg.Info("test"); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: string(fn), Err: err}))
+		}
+		if i < 3 {
+			if err := lg.Error(errors.New("test")); err != nil {
+				t.Error(tserr.Op(&tserr.OpArgs{Op: "Error", Fn: string(fn), Err: err}))
+			}
+		}
+	}
+	// Refill the token bucket
+	now = now.Add(time.Second)
+	// Log another message at Info level
+	if err := lg.Info("other"); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: string(fn), Err: err}))
+	}
+	// Record an error, if the number of discarded messages does not match
+	for lvl, want := range map[int]uint64{InfoLevel: 3, ErrorLevel: 0} {
+		if d := r.Dropped(lvl); d != want {
+			t.Error(tserr.Equal(&tserr.EqualArgs{Var: "Dropped", Actual: int64(d), Want: int64(want)}))
+		}
+	}
+	// Retrieve the logged messages
+	msgs := testMessages(t, fn)
+	// Record an error, if the number of logged messages is not eight
+	if len(msgs) != 8 {
+		t.Fatal(tserr.Equal(&tserr.EqualArgs{Var: "No. lines", Actual: int64(len(msgs)), Want: 8}))
+	}
+	// Record an error, if the number of discarded messages is not reported
+	if want := "3 entries discarded by rate limit"; msgs[6].Msg != want {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: want, Y: msgs[6].Msg}))
+	}
+	// Disable rate limiting
+	lg.SetRateLimiter(nil)
+}
+
+// TestRateLimiterClose limits Info level to one message per second, logs three messages at
+// Info level and closes the logger. The test fails if the number of discarded messages is not
+// reported on Close.
+func TestRateLimiterClose(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Create new rate limiter r with a fixed clock
+	r := NewRateLimiter()
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	// Limit Info level to one message per second
+	if err := r.SetLimit(InfoLevel, 1, 1); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set limit", Fn: "info", Err: err}))
+	}
+	// Set rate limiter r
+	lg.SetRateLimiter(r)
+	// Log three messages at Info level
+	for i := 0; i < 3; i++ {
+		if err := lg.Info("test"); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: string(fn), Err: err}))
+		}
+	}
+	// Close the logger
+	if err := lg.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: string(fn), Err: err}))
+	}
+	// Evaluate the logged messages
+	testWant(t, fn, []logmsg{
+		{Lvl: "info", Msg: "test"},
+		{Lvl: "info", Msg: "rate limit of 1 entries per second exceeded, discarding entries"},
+		{Lvl: "info", Msg: "2 entries discarded by rate limit"},
+	})
+}
+
+// TestSetLimitErr sets limits with invalid arguments. The test fails if SetLimit
+// does not return an error.
+func TestSetLimitErr(t *testing.T) {
+	// Create new rate limiter r
+	r := NewRateLimiter()
+	// Record an error, if SetLimit returns nil for an undefined level
+	if err := r.SetLimit(FatalLevel+1, 1, 1); err == nil {
+		t.Error(tserr.NilFailed("Set limit"))
+	}
+	// Record an error, if SetLimit returns nil for a negative rate
+	if err := r.SetLimit(InfoLevel, -1, 1); err == nil {
+		t.Error(tserr.NilFailed("Set limit"))
+	}
+	// Record an error, if SetLimit returns nil for a zero burst
+	if err := r.SetLimit(InfoLevel, 1, 0); err == nil {
+		t.Error(tserr.NilFailed("Set limit"))
+	}
+}
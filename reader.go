@@ -0,0 +1,127 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"bufio"         // bufio
+	"bytes"         // bytes
+	"encoding/json" // json
+	"errors"        // errors
+	"fmt"           // fmt
+	"io"            // io
+	"time"          // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// Reader reads the log messages in JSON format written by a logger as entries.
+type Reader struct {
+	r    *bufio.Reader // buffered input
+	b    []byte        // last read line
+	line int           // number of the last read line
+	eof  bool          // true, if the end of the input is reached
+	err  error         // error of reading from the input, if any
+}
+
+// ParseError is the error of a malformed or truncated line read by a Reader. Reading
+// continues with the next line.
+type ParseError struct {
+	Line int   // line number starting with 1
+	Err  error // error of parsing the line
+}
+
+// Error returns the error with the line number.
+func (e *ParseError) Error() string {
+	return tserr.Op(&tserr.OpArgs{Op: "parse", Fn: fmt.Sprintf("line %d", e.Line), Err: e.Err}).Error()
+}
+
+// Unwrap returns the error of parsing the line.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// NewReader creates a new Reader reading log messages in JSON format from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read reads the next log message and returns it as entry with the log level, the
+// timestamp parsed with the time layout of the logger, the message and the fields.
+// Empty lines are skipped. If a line is malformed or truncated, it returns nil and
+// a *ParseError with the line number, and the next call continues with the next line.
+// At the end of the input, it returns nil and io.EOF. If reading from the input fails,
+// it returns nil and the error for this and all subsequent calls.
+func (r *Reader) Read() (*Entry, error) {
+	for {
+		// Return the error of reading from the input, if any
+		if r.err != nil {
+			return nil, r.err
+		}
+		// Return io.EOF, if the end of the input is reached
+		if r.eof {
+			return nil, io.EOF
+		}
+		// Read the next line, including a last line without newline
+		b, err := r.r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			r.eof = true
+		} else if err != nil {
+			r.err = tserr.Op(&tserr.OpArgs{Op: "read", Fn: fmt.Sprintf("line %d", r.line+1), Err: err})
+			continue
+		}
+		// Skip the end of the input without a last line
+		if len(b) == 0 {
+			continue
+		}
+		// Count the line
+		r.line++
+		// Skip empty lines
+		if b = bytes.TrimSpace(b); len(b) == 0 {
+			continue
+		}
+		// Keep the line
+		r.b = b
+		// Return the entry of the line
+		return r.parse(b)
+	}
+}
+
+// Line returns the number of the last read line starting with 1.
+func (r *Reader) Line() int {
+	return r.line
+}
+
+// Bytes returns the last read line without surrounding white space, e.g. to re-emit
+// the log message unchanged. The line is not overwritten by subsequent calls of Read.
+func (r *Reader) Bytes() []byte {
+	return r.b
+}
+
+// parse returns the entry of log message b in JSON format. It returns nil and a *ParseError,
+// if b is malformed, the log level is not defined or the timestamp is invalid.
+func (r *Reader) parse(b []byte) (*Entry, error) {
+	// Unmarshal the log message
+	var w logwrap
+	if err := json.Unmarshal(b, &w); err != nil {
+		return nil, r.error(err)
+	}
+	// Retrieve the log level
+	lvl, err := ParseLevel(w.L.Lvl)
+	if err != nil {
+		return nil, r.error(err)
+	}
+	// Parse the timestamp
+	t, err := time.Parse(timeLayout, w.L.Now)
+	if err != nil {
+		return nil, r.error(err)
+	}
+	// Return the entry
+	return &Entry{Level: lvl, Message: w.L.Msg, Time: t, Fields: w.L.Fields}, nil
+}
+
+// error returns a *ParseError of the last read line with err.
+func (r *Reader) error(err error) error {
+	return &ParseError{Line: r.line, Err: err}
+}
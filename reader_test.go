@@ -0,0 +1,104 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages, tserr and tsfio.
+import (
+	"bytes"   // bytes
+	"errors"  // errors
+	"fmt"     // fmt
+	"io"      // io
+	"strings" // strings
+	"testing" // testing
+	"time"    // time
+
+	"github.com/thorstenrie/tserr" // tserr
+	"github.com/thorstenrie/tsfio" // tsfio
+)
+
+// TestReader logs entries with a field, appends a malformed line, an empty line and a
+// truncated line and reads them back. The test fails if the entries do not match or the
+// malformed and truncated lines are not reported with their line numbers.
+func TestReader(t *testing.T) {
+	// Create new logger lg logging to the temporary file fn
+	lg, fn := traceLogger(t)
+	lg.AddProcessor(func(e *Entry) bool {
+		e.SetField("tenant", "a")
+		return true
+	})
+	// Log entries
+	lg.Debug("d1")
+	lg.Error(errors.New("e1"))
+	// Retrieve the log messages and append a malformed line, an empty line and a truncated line
+	b, err := tsfio.ReadFile(fn)
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "ReadFile", Fn: string(fn), Err: err}))
+	}
+	rm(t, fn)
+	b = append(b, []byte("malformed\n\n"+`{"log":{"level":"info","mess`)...)
+	// Read the log messages
+	r := NewReader(bytes.NewReader(b))
+	// got holds the read entries and errors
+	var got []string
+	for {
+		e, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			got = append(got, fmt.Sprintf("error %d", r.Line()))
+			continue
+		}
+		// Record an error, if the timestamp is not parsed
+		if time.Since(e.Time) > time.Minute {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "now", Y: e.Time.String()}))
+		}
+		got = append(got, fmt.Sprintf("%d %s %v", e.Level, e.Message, e.Fields["tenant"]))
+		// Record an error, if the last read line does not contain the message
+		if !bytes.Contains(r.Bytes(), []byte(e.Message)) {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: e.Message, Y: string(r.Bytes())}))
+		}
+	}
+	// Record an error, if the entries and errors do not match
+	want := fmt.Sprintf("%d d1 a, %d e1 a, error 3, error 5", DebugLevel, ErrorLevel)
+	if s := strings.Join(got, ", "); s != want {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: want, Y: s}))
+	}
+}
+
+// TestReaderLevel reads a log message with an undefined log level and a log message with
+// an invalid timestamp. The test fails if Read does not return an error.
+func TestReaderLevel(t *testing.T) {
+	// Iterate log messages with an undefined log level and an invalid timestamp
+	for _, m := range []string{`{"log":{"level":"none","message":"m","time":"2023-01-02 15:04:05 +0000 UTC"}}`, `{"log":{"level":"info","message":"m","time":"now"}}`} {
+		// Record an error, if Read returns nil
+		if _, err := NewReader(strings.NewReader(m)).Read(); err == nil {
+			t.Error(tserr.NilFailed("Read"))
+		}
+	}
+}
+
+// errReader is an io.Reader failing on each call.
+type errReader struct{}
+
+// Read always returns an error.
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+// TestReaderErr reads from a failing input. The test fails if Read does not return the read
+// error on each call or returns a *ParseError.
+func TestReaderErr(t *testing.T) {
+	// Create the reader on the failing input
+	r := NewReader(errReader{})
+	// Read twice
+	for i := 0; i < 2; i++ {
+		_, err := r.Read()
+		// Record an error, if Read returns nil, io.EOF or a *ParseError
+		var perr *ParseError
+		if (err == nil) || errors.Is(err, io.EOF) || errors.As(err, &perr) {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "read failed", Y: fmt.Sprint(err)}))
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"fmt"  // fmt
+	"sync" // sync
+	"time" // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// recorded is an entry kept by a Recorder.
+type recorded struct {
+	e      Entry  // entry
+	line   []byte // encoded entry
+	logged bool   // true, if the entry was logged to the output
+}
+
+// Recorder is a flight recorder keeping the last entries in a fixed-size ring buffer.
+// Set with SetRecorder, it keeps the logged entries and the entries below the minimum
+// level of the logger down to the level of the recorder. With dump, the recorded
+// entries below the minimum level are written to the output of the logger before an
+// entry at Error or Fatal level. Set with SetSink, it keeps the logged entries in
+// memory only.
+type Recorder struct {
+	mu   sync.Mutex // mutex for the ring buffer
+	buf  []recorded // ring buffer
+	next int        // index of the next entry in the ring buffer
+	full bool       // true, if the ring buffer is full
+	lvl  int        // minimum level of recorded entries
+	dump bool       // dump recorded entries before Error and Fatal entries
+}
+
+// NewRecorder creates a new Recorder keeping the last size entries with a level equal to or
+// higher than lvl. If dump is true, recorded entries are dumped before Error and Fatal entries.
+// It returns an error, if size is not positive or lvl is not a defined log level.
+func NewRecorder(size, lvl int, dump bool) (*Recorder, error) {
+	// Return an error, if size is not positive
+	if size < 1 {
+		return nil, tserr.NotExistent(fmt.Sprintf("recorder size %d", size))
+	}
+	// Return an error, if lvl is not a defined log level
+	if _, err := level(lvl); err != nil {
+		return nil, err
+	}
+	// Return the recorder
+	return &Recorder{buf: make([]recorded, size), lvl: lvl, dump: dump}, nil
+}
+
+// SetRecorder sets recorder r as flight recorder of the logger. If r is nil, recording
+// is disabled.
+func (l *Logger) SetRecorder(r *Recorder) {
+	// Lock the configuration of encoding
+	l.out.Lock()
+	// Unlock the configuration of encoding on return
+	defer l.out.Unlock()
+	// Set the recorder
+	l.recorder = r
+}
+
+// Snapshot returns copies of the recorded entries with copies of their fields from the oldest
+// to the newest entry.
+func (r *Recorder) Snapshot() []Entry {
+	// Lock the ring buffer
+	r.mu.Lock()
+	// Unlock the ring buffer on return
+	defer r.mu.Unlock()
+	// s holds the recorded entries
+	s := make([]Entry, 0, len(r.buf))
+	// Append copies of the recorded entries from the oldest to the newest entry
+	r.each(func(rec *recorded) {
+		s = append(s, rec.e.clone())
+	})
+	// Return the recorded entries
+	return s
+}
+
+// Log records logged entry e encoded as line. It always returns nil.
+func (r *Recorder) Log(e *Entry, line []byte) error {
+	// Record e without dumping
+	r.mu.Lock()
+	r.add(e, line, true)
+	r.mu.Unlock()
+	// Return nil
+	return nil
+}
+
+// Close always returns nil. The recorded entries are kept.
+func (r *Recorder) Close() error {
+	return nil
+}
+
+// record records entry e encoded as line, which is logged, if logged is true. If dump is set and e
+// is an Error or Fatal entry, it returns the recorded entries, which were not logged, and marks
+// them as logged.
+func (r *Recorder) record(e *Entry, line []byte, logged bool) []recorded {
+	// Lock the ring buffer
+	r.mu.Lock()
+	// Unlock the ring buffer on return
+	defer r.mu.Unlock()
+	// d holds the entries to be dumped
+	var d []recorded
+	// Retrieve the entries to be dumped, if requested
+	if r.dump && logged && (e.Level >= ErrorLevel) {
+		r.each(func(rec *recorded) {
+			if !rec.logged {
+				d = append(d, *rec)
+				rec.logged = true
+			}
+		})
+	}
+	// Record e
+	r.add(e, line, logged)
+	// Return the entries to be dumped
+	return d
+}
+
+// add adds entry e encoded as line to the ring buffer and overwrites the oldest entry, if
+// the ring buffer is full. The ring buffer must be locked.
+func (r *Recorder) add(e *Entry, line []byte, logged bool) {
+	// Add a copy of e
+	r.buf[r.next] = recorded{e: *e, line: line, logged: logged}
+	// Advance to the next index
+	if r.next++; r.next == len(r.buf) {
+		r.next, r.full = 0, true
+	}
+}
+
+// each calls f for each recorded entry from the oldest to the newest entry. The ring buffer must be locked.
+func (r *Recorder) each(f func(rec *recorded)) {
+	// Start with the oldest entry
+	i, n := 0, r.next
+	if r.full {
+		i, n = r.next, len(r.buf)
+	}
+	// Call f for each recorded entry
+	for k := 0; k < n; k++ {
+		f(&r.buf[(i+k)%len(r.buf)])
+	}
+}
+
+// record creates an entry at level lvl with message msg below the minimum level, executes the
+// processors, encodes it and records it with recorder r without logging it.
+func (l *Logger) record(r *Recorder, lvl int, msg string) {
+	// Create the log entry
+	e := &Entry{Level: lvl, Message: msg, Time: time.Now()}
+	// Return, if the entry is dropped by a processor
+	if ok, _ := l.process(e); !ok {
+		return
+	}
+	// Record the entry, if encoding succeeds
+	if j, err := l.encode(e); err == nil {
+		r.record(e, j, false)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"errors"  // errors
+	"fmt"     // fmt
+	"strings" // strings
+	"testing" // testing
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// TestRecorderDump logs Debug entries below the minimum level, an Info entry and two Error
+// entries with a recorder of three entries dumping on errors. The test fails if the recorded
+// Debug entries are not dumped once before the first Error entry or the snapshot does not
+// contain the last three entries.
+func TestRecorderDump(t *testing.T) {
+	// Create new logger lg logging to the temporary file fn at Info level
+	lg, fn := traceLogger(t)
+	if err := lg.SetLevel(InfoLevel); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set level", Fn: string(fn), Err: err}))
+	}
+	// Create the recorder r for the last three entries from Trace level
+	r, err := NewRecorder(3, TraceLevel, true)
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New recorder", Fn: string(fn), Err: err}))
+	}
+	// Set the recorder
+	lg.SetRecorder(r)
+	// Log four Debug entries, an Info entry and two Error entries
+	for _, m := range []string{"d1", "d2", "d3", "d4"} {
+		lg.Debug(m)
+	}
+	lg.Info("i1")
+	lg.Error(errors.New("e1"))
+	lg.Error(errors.New("e2"))
+	// Record an error, if the snapshot does not contain the last three entries
+	if s := testSnapshot(r); s != "i1 e1 e2" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "i1 e1 e2", Y: s}))
+	}
+	// Retrieve the logged messages
+	var logged []string
+	for _, m := range testMessages(t, fn) {
+		logged = append(logged, m.Msg)
+	}
+	// Record an error, if the recorded Debug entries are not dumped once before the first Error entry
+	if s := strings.Join(logged, " "); s != "i1 d3 d4 e1 e2" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "i1 d3 d4 e1 e2", Y: s}))
+	}
+}
+
+// TestRecorderSink logs three entries with a recorder of two entries as sink. The test fails
+// if the snapshot does not contain the last two entries.
+func TestRecorderSink(t *testing.T) {
+	// Create the recorder r for the last two entries
+	r, err := NewRecorder(2, TraceLevel, false)
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New recorder", Fn: "sink", Err: err}))
+	}
+	// Create new logger lg with recorder r as sink
+	lg := New()
+	if err := lg.SetSink(r); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set sink", Fn: "recorder", Err: err}))
+	}
+	// Log three entries
+	for _, m := range []string{"i1", "i2", "i3"} {
+		lg.Info(m)
+	}
+	// Record an error, if the snapshot does not contain the last two entries
+	if s := testSnapshot(r); s != "i2 i3" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "i2 i3", Y: s}))
+	}
+}
+
+// TestRecorderSnapshot modifies the fields of a snapshot of a recorder. The test fails if
+// the fields of the recorded entry change.
+func TestRecorderSnapshot(t *testing.T) {
+	// Create the recorder r for one entry
+	r, err := NewRecorder(1, TraceLevel, false)
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New recorder", Fn: "snapshot", Err: err}))
+	}
+	// Record an entry with a field
+	e := &Entry{Level: InfoLevel, Message: "i1"}
+	e.SetField("k", "v")
+	r.Log(e, nil)
+	// Modify the field of the snapshot
+	r.Snapshot()[0].Fields["k"] = "changed"
+	// Record an error, if the field of the recorded entry changed
+	if v := r.Snapshot()[0].Fields["k"]; v != "v" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "v", Y: fmt.Sprint(v)}))
+	}
+}
+
+// TestRecorderErr creates recorders with invalid sizes and levels. The test fails if
+// NewRecorder does not return an error.
+func TestRecorderErr(t *testing.T) {
+	// Record an error, if NewRecorder returns nil for a size of zero
+	if _, err := NewRecorder(0, TraceLevel, false); err == nil {
+		t.Error(tserr.NilFailed("New recorder"))
+	}
+	// Record an error, if NewRecorder returns nil for an undefined level
+	if _, err := NewRecorder(1, 0, false); err == nil {
+		t.Error(tserr.NilFailed("New recorder"))
+	}
+}
+
+// testSnapshot returns the messages of the snapshot of recorder r separated by spaces.
+func testSnapshot(r *Recorder) string {
+	// msgs holds the messages
+	var msgs []string
+	// Append the message of each recorded entry
+	for _, e := range r.Snapshot() {
+		msgs = append(msgs, e.Message)
+	}
+	// Return the messages separated by spaces
+	return strings.Join(msgs, " ")
+}
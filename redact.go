@@ -0,0 +1,131 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"fmt"     // fmt
+	"regexp"  // regexp
+	"strings" // strings
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// Secret is a string which always renders as *** wherever it is logged,
+// e.g. formatted in a message with package fmt or as value of a field.
+type Secret string
+
+// Redactor masks values of fields with configured keys and matches of configured
+// patterns in messages and string values of fields. A Redactor is set on a logger with
+// SetRedactor.
+type Redactor struct {
+	keys     map[string]bool  // lower case keys of masked fields
+	patterns []*regexp.Regexp // patterns masked in messages and string values of fields
+}
+
+// Mask replaces redacted values
+const Mask string = "***"
+
+// Patterns for common secrets
+const (
+	BearerPattern string = `(?i)bearer\s+[a-z0-9\-._~+/]+=*` // bearer tokens
+	CardPattern   string = `\b(?:\d[ -]?){12,18}\d\b`        // credit card numbers
+)
+
+// String returns the mask.
+func (s Secret) String() string {
+	return Mask
+}
+
+// GoString returns the mask.
+func (s Secret) GoString() string {
+	return Mask
+}
+
+// Format writes the mask for all verbs.
+func (s Secret) Format(f fmt.State, verb rune) {
+	f.Write([]byte(Mask))
+}
+
+// MarshalText returns the mask.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(Mask), nil
+}
+
+// NewRedactor creates a new Redactor without keys and patterns. Keys are
+// added with AddKey and patterns with AddPattern.
+func NewRedactor() *Redactor {
+	return &Redactor{keys: make(map[string]bool)}
+}
+
+// AddKey adds key to the keys of masked fields. Keys are case insensitive.
+func (r *Redactor) AddKey(key string) {
+	r.keys[strings.ToLower(key)] = true
+}
+
+// AddPattern adds the regular expression expr to the patterns masked in messages and
+// string values of fields. It returns an error, if expr cannot be compiled.
+func (r *Redactor) AddPattern(expr string) error {
+	// Compile expr
+	re, err := regexp.Compile(expr)
+	// Return an error, if compilation fails
+	if err != nil {
+		return tserr.Check(&tserr.CheckArgs{F: expr, Err: err})
+	}
+	// Append the pattern
+	r.patterns = append(r.patterns, re)
+	// Return nil
+	return nil
+}
+
+// SetRedactor sets redactor r for the logger. Redaction applies to each entry before
+// it is encoded. If r is nil, redaction is disabled.
+func (l *Logger) SetRedactor(r *Redactor) {
+	// Lock the configuration of encoding
+	l.out.Lock()
+	// Unlock the configuration of encoding on return
+	defer l.out.Unlock()
+	// Set the redactor
+	l.redactor = r
+}
+
+// redact masks the message and the fields of e. The fields are copied to
+// keep the fields provided by processors unchanged.
+func (r *Redactor) redact(e *Entry) {
+	// Mask patterns in the message
+	e.Message = r.mask(e.Message)
+	// Return, if e has no fields
+	if len(e.Fields) == 0 {
+		return
+	}
+	// f holds the redacted fields
+	f := make(map[string]any, len(e.Fields))
+	// Iterate all fields
+	for k, v := range e.Fields {
+		// Mask the value, if the key is configured
+		if r.keys[strings.ToLower(k)] {
+			f[k] = Mask
+			continue
+		}
+		// Mask patterns in string values
+		if s, ok := v.(string); ok {
+			v = r.mask(s)
+		}
+		// Keep the value
+		f[k] = v
+	}
+	// Set the redacted fields
+	e.Fields = f
+}
+
+// mask replaces all matches of the patterns in s with the mask.
+func (r *Redactor) mask(s string) string {
+	// Iterate all patterns
+	for _, re := range r.patterns {
+		// Replace matches with the mask
+		s = re.ReplaceAllLiteralString(s, Mask)
+	}
+	// Return s
+	return s
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"errors"  // errors
+	"fmt"     // fmt
+	"testing" // testing
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// TestRedactor logs messages with a bearer token and a credit card number and fields with
+// a password, a Secret and a bearer token. The test fails if a secret is not masked.
+func TestRedactor(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Create new redactor r for key password and patterns for bearer tokens and credit card numbers
+	r := NewRedactor()
+	r.AddKey("Password")
+	for _, p := range []string{BearerPattern, CardPattern} {
+		if err := r.AddPattern(p); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Add pattern", Fn: p, Err: err}))
+		}
+	}
+	// Set redactor r
+	lg.SetRedactor(r)
+	// Add processor setting fields with secrets
+	lg.AddProcessor(func(e *Entry) bool {
+		e.SetField("password", "1234")
+		e.SetField("token", Secret("1234"))
+		e.SetField("header", "Bearer abc.def")
+		return true
+	})
+	// Log messages with secrets at Info and Error level
+	msg := "auth Bearer xyz card 4111 1111 1111 1111 ok"
+	if err := lg.Info(msg); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: string(fn), Err: err}))
+	}
+	if err := lg.Error(errors.New(msg)); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Error", Fn: string(fn), Err: err}))
+	}
+	// Retrieve the logged messages
+	msgs := testMessages(t, fn)
+	// Stop execution, if the number of logged messages is not two
+	if len(msgs) != 2 {
+		t.Fatal(tserr.Equal(&tserr.EqualArgs{Var: "No. lines", Actual: int64(len(msgs)), Want: 2}))
+	}
+	// Iterate logged messages
+	for _, m := range msgs {
+		// Record an error, if the message is not masked
+		if want := "auth *** card *** ok"; m.Msg != want {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: want, Y: m.Msg}))
+		}
+		// Record an error, if a field is not masked
+		for _, k := range []string{"password", "token", "header"} {
+			if v := fmt.Sprint(m.Fields[k]); v != Mask {
+				t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: Mask, Y: v}))
+			}
+		}
+	}
+}
+
+// TestSecret formats a Secret with different verbs. The test fails if the Secret is not masked.
+func TestSecret(t *testing.T) {
+	// Create Secret s
+	s := Secret("1234")
+	// Iterate verbs
+	for _, v := range []string{"%v", "%s", "%q", "%#v", "%x"} {
+		// Record an error, if s is not masked
+		if f := fmt.Sprintf(v, s); f != Mask {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: Mask, Y: f}))
+		}
+	}
+}
+
+// TestAddPatternErr adds a malformed pattern. The test fails if AddPattern does not return an error.
+func TestAddPatternErr(t *testing.T) {
+	// Record an error, if AddPattern returns nil
+	if err := NewRedactor().AddPattern("["); err == nil {
+		t.Error(tserr.NilFailed("Add pattern"))
+	}
+}
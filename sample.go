@@ -0,0 +1,136 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"fmt"         // fmt
+	"sync"        // sync
+	"sync/atomic" // atomic
+	"time"        // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// Sampler samples log messages with the same level and message. In each interval,
+// the first messages are logged and thereafter every Mth message. All other messages
+// are dropped. The number of dropped messages per level is reported with the first
+// log message after the interval. A Sampler is set on a logger with SetSampler.
+type Sampler struct {
+	first      int                    // number of logged messages per interval
+	thereafter int                    // log every Mth message after first messages
+	tick       time.Duration          // length of the interval
+	mu         sync.Mutex             // mutex for the sampling state
+	start      time.Time              // start of the current interval
+	counts     map[samplekey]int      // number of messages in the current interval
+	dropped    [FatalLevel + 1]uint64 // number of dropped messages per level in the current interval
+	total      atomic.Uint64          // total number of dropped messages
+	now        func() time.Time       // clock
+}
+
+// samplekey identifies messages with the same level, message and fields.
+type samplekey struct {
+	lvl    int    // log level
+	msg    string // log message
+	fields string // encoded fields, empty for sampling
+}
+
+// NewSampler creates a new Sampler logging the first messages with the same level and message in
+// each interval tick and thereafter every Mth message. If thereafter is zero, all messages
+// after the first messages are dropped. It returns an error, if first or thereafter are
+// negative or tick is not positive.
+func NewSampler(first, thereafter int, tick time.Duration) (*Sampler, error) {
+	// Return an error, if first or thereafter are negative
+	if (first < 0) || (thereafter < 0) {
+		return nil, tserr.NotExistent(fmt.Sprintf("sampling of first %d and thereafter %d", first, thereafter))
+	}
+	// Return an error, if tick is not positive
+	if tick <= 0 {
+		return nil, tserr.NotExistent(fmt.Sprintf("sampling interval %v", tick))
+	}
+	// Return the new Sampler
+	return &Sampler{first: first, thereafter: thereafter, tick: tick, counts: make(map[samplekey]int), now: time.Now}, nil
+}
+
+// Dropped returns the total number of messages dropped by s.
+func (s *Sampler) Dropped() uint64 {
+	return s.total.Load()
+}
+
+// SetSampler sets sampler s for the logger. Sampling applies to messages with levels equal
+// to or higher than the minimum level. If s is nil, sampling is disabled.
+func (l *Logger) SetSampler(s *Sampler) {
+	l.sampler = s
+}
+
+// sample counts the message msg at level lvl. It returns true, if the message is
+// logged and false, if the message is dropped. If a new interval starts, sample returns
+// the number of dropped messages per level of the previous interval, otherwise nil.
+func (s *Sampler) sample(lvl int, msg string) (bool, []uint64) {
+	// Lock the sampling state
+	s.mu.Lock()
+	// Unlock the sampling state on return
+	defer s.mu.Unlock()
+	// rep holds the number of dropped messages of the previous interval
+	var rep []uint64
+	// Retrieve the current time
+	now := s.now()
+	// Start a new interval, if the current interval expired
+	if now.Sub(s.start) >= s.tick {
+		// Report dropped messages of the previous interval
+		rep = s.flush()
+		// Reset the message counts
+		s.counts = make(map[samplekey]int)
+		// Set the start of the new interval
+		s.start = now
+	}
+	// Count the message
+	k := samplekey{lvl: lvl, msg: msg}
+	s.counts[k]++
+	n := s.counts[k]
+	// Log the first messages and thereafter every Mth message
+	if (n <= s.first) || ((s.thereafter > 0) && ((n-s.first)%s.thereafter == 0)) {
+		return true, rep
+	}
+	// Count the dropped message
+	if (lvl >= TraceLevel) && (lvl <= FatalLevel) {
+		s.dropped[lvl]++
+	}
+	s.total.Add(1)
+	// Drop the message
+	return false, rep
+}
+
+// flush returns the number of dropped messages per level and resets them. It returns
+// nil, if no messages were dropped. The sampling state must be locked.
+func (s *Sampler) flush() []uint64 {
+	// rep holds the number of dropped messages per level
+	var rep []uint64
+	// Iterate all levels
+	for lvl, n := range s.dropped {
+		// Skip levels without dropped messages
+		if n == 0 {
+			continue
+		}
+		// Allocate rep for the first level with dropped messages
+		if rep == nil {
+			rep = make([]uint64, len(s.dropped))
+		}
+		// Move the number of dropped messages to rep
+		rep[lvl], s.dropped[lvl] = n, 0
+	}
+	// Return the number of dropped messages
+	return rep
+}
+
+// reportSampled logs the number of dropped messages per level in rep at the respective level.
+func (l *Logger) reportSampled(rep []uint64) {
+	// Iterate all levels
+	for lvl, n := range rep {
+		// Log the number of dropped messages, if any
+		if n > 0 {
+			l.notice(lvl, fmt.Sprintf("%d entries suppressed by sampling", n))
+		}
+	}
+}
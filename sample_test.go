@@ -0,0 +1,73 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"testing" // testing
+	"time"    // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// TestSampler logs the same message ten times with a sampler logging the first two
+// and thereafter every third message. After the interval expired, it logs another message.
+// The test fails if the number of logged lines, the number of dropped messages or the
+// report of the dropped messages does not match the expected result.
+func TestSampler(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Create new sampler s
+	s, err := NewSampler(2, 3, time.Second)
+	// Stop execution, if NewSampler fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New sampler", Fn: string(fn), Err: err}))
+	}
+	// Set a fixed clock
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	// Set sampler s
+	lg.SetSampler(s)
+	// Log the same message ten times
+	for i := 0; i < 10; i++ {
+		if err := lg.Debug("test"); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Debug", Fn: string(fn), Err: err}))
+		}
+	}
+	// Record an error, if the number of dropped messages is not six
+	if d := s.Dropped(); d != 6 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "Dropped", Actual: int64(d), Want: 6}))
+	}
+	// Expire the interval
+	now = now.Add(time.Second)
+	// Log another message
+	if err := lg.Info("other"); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: string(fn), Err: err}))
+	}
+	// Retrieve the logged messages
+	msgs := testMessages(t, fn)
+	// Record an error, if the number of logged messages is not six
+	if len(msgs) != 6 {
+		t.Fatal(tserr.Equal(&tserr.EqualArgs{Var: "No. lines", Actual: int64(len(msgs)), Want: 6}))
+	}
+	// Record an error, if the dropped messages are not reported at Debug level
+	if want := (logmsg{Lvl: "debug", Msg: "6 entries suppressed by sampling"}); (msgs[4].Lvl != want.Lvl) || (msgs[4].Msg != want.Msg) {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: want.Msg, Y: msgs[4].Msg}))
+	}
+	// Disable sampling
+	lg.SetSampler(nil)
+}
+
+// TestNewSamplerErr creates samplers with invalid arguments. The test fails if
+// NewSampler does not return an error.
+func TestNewSamplerErr(t *testing.T) {
+	// Record an error, if NewSampler returns nil for negative first
+	if _, err := NewSampler(-1, 1, time.Second); err == nil {
+		t.Error(tserr.NilFailed("New sampler"))
+	}
+	// Record an error, if NewSampler returns nil for a zero interval
+	if _, err := NewSampler(1, 1, 0); err == nil {
+		t.Error(tserr.NilFailed("New sampler"))
+	}
+}
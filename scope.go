@@ -0,0 +1,183 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"fmt"  // fmt
+	"sync" // sync
+	"time" // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// Defaults for scopes
+const (
+	// Estimated memory in bytes of a buffered entry in addition to its message
+	scopeOverhead int = 64
+)
+
+// Scope is a logger scope, e.g. of a request, derived from a logger. Entries below the
+// minimum level of the logger down to the level of the scope are buffered in memory. If
+// the scope logs an entry at Warn level or higher, the buffered entries are written to
+// the output of the logger before it and subsequent entries of the scope down to the level
+// of the scope are written directly. Otherwise, the buffered entries are discarded with
+// Discard or written with Commit at the end of the scope. If the buffered entries exceed
+// the memory limit of the scope, the oldest entries are dropped. Entries at or above the
+// minimum level of the logger are logged as usual.
+type Scope struct {
+	l         *Logger    // logger
+	mu        sync.Mutex // mutex for the buffer
+	buf       []Entry    // buffered entries
+	size      int        // estimated memory of the buffered entries in bytes
+	max       int        // memory limit of the buffered entries in bytes
+	lvl       int        // minimum level of buffered entries
+	triggered bool       // true, if the scope logged an entry at Warn level or higher
+	dropped   int        // number of dropped entries
+}
+
+// NewScope creates a new Scope of the logger buffering entries with a level equal to or
+// higher than lvl within a memory limit of max bytes. It returns an error, if lvl is not
+// a defined log level or max is not positive.
+func (l *Logger) NewScope(lvl, max int) (*Scope, error) {
+	// Return an error, if lvl is not a defined log level
+	if _, err := level(lvl); err != nil {
+		return nil, err
+	}
+	// Return an error, if max is not positive
+	if max < 1 {
+		return nil, tserr.NotExistent(fmt.Sprintf("scope memory limit %d", max))
+	}
+	// Return the scope
+	return &Scope{l: l, max: max, lvl: lvl}, nil
+}
+
+// Trace logs a message at Trace level in the scope. It returns an error if JSON encoding of msg fails.
+func (s *Scope) Trace(msg string) error {
+	return s.log(TraceLevel, msg)
+}
+
+// Debug logs a message at Debug level in the scope. It returns an error if JSON encoding of msg fails.
+func (s *Scope) Debug(msg string) error {
+	return s.log(DebugLevel, msg)
+}
+
+// Info logs a message at Info level in the scope. It returns an error if JSON encoding of msg fails.
+func (s *Scope) Info(msg string) error {
+	return s.log(InfoLevel, msg)
+}
+
+// Warn logs a message at Warn level in the scope. It returns an error if JSON encoding of msg fails.
+func (s *Scope) Warn(msg string) error {
+	return s.log(WarnLevel, msg)
+}
+
+// Error logs error err at Error level in the scope. It returns an error if JSON encoding of msg fails.
+func (s *Scope) Error(err error) error {
+	return s.log(ErrorLevel, err.Error())
+}
+
+// Fatal logs error err at Fatal level in the scope. It returns an error if JSON encoding of msg fails.
+func (s *Scope) Fatal(err error) error {
+	return s.log(FatalLevel, err.Error())
+}
+
+// Commit writes the buffered entries to the output of the logger and empties the buffer.
+// It returns the first error of writing the entries, if any.
+func (s *Scope) Commit() error {
+	// Lock the buffer
+	s.mu.Lock()
+	// Unlock the buffer on return
+	defer s.mu.Unlock()
+	// Write the buffered entries
+	return s.flush()
+}
+
+// Discard discards the buffered entries.
+func (s *Scope) Discard() {
+	// Lock the buffer
+	s.mu.Lock()
+	// Unlock the buffer on return
+	defer s.mu.Unlock()
+	// Empty the buffer
+	s.buf, s.size = nil, 0
+}
+
+// Dropped returns the number of entries dropped due to the memory limit of the scope.
+func (s *Scope) Dropped() int {
+	// Lock the buffer
+	s.mu.Lock()
+	// Unlock the buffer on return
+	defer s.mu.Unlock()
+	// Return the number of dropped entries
+	return s.dropped
+}
+
+// log logs message msg at level lvl in the scope. Entries below the level of the scope and
+// entries at or above the minimum level of the logger below Warn level are logged as usual.
+// An entry at Warn level or higher writes the buffered entries first. Other entries are
+// written directly, if the scope was triggered, or buffered otherwise.
+func (s *Scope) log(lvl int, msg string) error {
+	// Log the entry as usual, if it is not affected by the scope
+	if (lvl < s.lvl) || ((lvl >= s.l.minLvl) && (lvl < WarnLevel)) {
+		return s.l.tryLog(lvl, msg)
+	}
+	// Lock the buffer
+	s.mu.Lock()
+	// Unlock the buffer on return
+	defer s.mu.Unlock()
+	// Trigger the scope and write the buffered entries, if lvl is Warn level or higher
+	if (lvl >= WarnLevel) && !s.triggered {
+		s.triggered = true
+		if err := s.flush(); err != nil {
+			return err
+		}
+	}
+	// Log the entry as usual, if lvl is equal to or higher than the minimum log level
+	if lvl >= s.l.minLvl {
+		return s.l.tryLog(lvl, msg)
+	}
+	// Create the log entry
+	e := Entry{Level: lvl, Message: msg, Time: time.Now()}
+	// Write the entry directly, if the scope was triggered
+	if s.triggered {
+		return s.l.emit(&e)
+	}
+	// Buffer the entry
+	s.add(e)
+	// Return nil
+	return nil
+}
+
+// add adds entry e to the buffer and drops the oldest entries exceeding the memory limit.
+// The buffer must be locked.
+func (s *Scope) add(e Entry) {
+	// Add e
+	s.buf = append(s.buf, e)
+	s.size += len(e.Message) + scopeOverhead
+	// Drop the oldest entries exceeding the memory limit
+	for (s.size > s.max) && (len(s.buf) > 0) {
+		s.size -= len(s.buf[0].Message) + scopeOverhead
+		s.buf[0] = Entry{}
+		s.buf = s.buf[1:]
+		s.dropped++
+	}
+}
+
+// flush writes the buffered entries to the output of the logger and empties the buffer.
+// It returns the first error of writing the entries, if any. The buffer must be locked.
+func (s *Scope) flush() error {
+	// errs holds the first error, if any
+	var errs error
+	// Write the buffered entries
+	for i := range s.buf {
+		if err := s.l.emit(&s.buf[i]); (err != nil) && (errs == nil) {
+			errs = err
+		}
+	}
+	// Empty the buffer
+	s.buf, s.size = nil, 0
+	// Return the first error, if any
+	return errs
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages, tserr and tsfio.
+import (
+	"strings" // strings
+	"testing" // testing
+
+	"github.com/thorstenrie/tserr" // tserr
+	"github.com/thorstenrie/tsfio" // tsfio
+)
+
+// TestScopeTrigger logs Debug entries, an Info entry and a Warn entry in a scope of a logger
+// at Info level. The test fails if the buffered Debug entries are not written before the Warn
+// entry or the subsequent Debug entry is not written directly.
+func TestScopeTrigger(t *testing.T) {
+	// Create the scope s of logger lg at Info level buffering from Debug level
+	s, fn := testScope(t, 1024)
+	// Log Debug entries, an Info entry, a Warn entry and a Debug entry
+	s.Debug("d1")
+	s.Info("i1")
+	s.Debug("d2")
+	s.Warn("w1")
+	s.Debug("d3")
+	// Record an error, if the entries are not written in the expected order
+	if m := testScopeMessages(t, fn); m != "i1 d1 d2 w1 d3" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "i1 d1 d2 w1 d3", Y: m}))
+	}
+}
+
+// TestScopeCommit logs Debug entries in two scopes and commits one and discards the other. The
+// test fails if the Debug entries of the discarded scope are written or the Debug entries of the
+// committed scope are not written.
+func TestScopeCommit(t *testing.T) {
+	// Create the scope s of logger lg at Info level buffering from Debug level
+	s, fn := testScope(t, 1024)
+	// Log a Debug entry and discard it
+	s.Debug("d1")
+	s.Discard()
+	// Log a Debug entry and commit it
+	s.Debug("d2")
+	if err := s.Commit(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Commit", Fn: string(fn), Err: err}))
+	}
+	// Record an error, if only the committed entry is not written
+	if m := testScopeMessages(t, fn); m != "d2" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "d2", Y: m}))
+	}
+}
+
+// TestScopeMax logs three Debug entries in a scope with a memory limit of two entries. The
+// test fails if the oldest entry is not dropped.
+func TestScopeMax(t *testing.T) {
+	// Create the scope s of logger lg at Info level with a memory limit of two entries
+	s, fn := testScope(t, 2*(scopeOverhead+2))
+	// Log three Debug entries and commit them
+	for _, m := range []string{"d1", "d2", "d3"} {
+		s.Debug(m)
+	}
+	s.Commit()
+	// Record an error, if the number of dropped entries is not one
+	if d := s.Dropped(); d != 1 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "dropped", Actual: int64(d), Want: 1}))
+	}
+	// Record an error, if the oldest entry is not dropped
+	if m := testScopeMessages(t, fn); m != "d2 d3" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "d2 d3", Y: m}))
+	}
+}
+
+// TestScopeErr creates scopes with an undefined level and an invalid memory limit. The
+// test fails if NewScope does not return an error.
+func TestScopeErr(t *testing.T) {
+	// Record an error, if NewScope returns nil for an undefined level
+	if _, err := New().NewScope(0, 1024); err == nil {
+		t.Error(tserr.NilFailed("New scope"))
+	}
+	// Record an error, if NewScope returns nil for a memory limit of zero
+	if _, err := New().NewScope(DebugLevel, 0); err == nil {
+		t.Error(tserr.NilFailed("New scope"))
+	}
+}
+
+// testScope creates a logger at Info level logging to a temporary file and a scope buffering
+// from Debug level within memory limit max. It returns the scope and the filename.
+func testScope(t *testing.T, max int) (*Scope, tsfio.Filename) {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Create new logger lg logging to the temporary file fn at Info level
+	lg, fn := traceLogger(t)
+	if err := lg.SetLevel(InfoLevel); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set level", Fn: string(fn), Err: err}))
+	}
+	// Create the scope s
+	s, err := lg.NewScope(DebugLevel, max)
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New scope", Fn: string(fn), Err: err}))
+	}
+	// Return the scope and the filename
+	return s, fn
+}
+
+// testScopeMessages returns the messages logged to file fn separated by spaces.
+func testScopeMessages(t *testing.T, fn tsfio.Filename) string {
+	// msgs holds the messages
+	var msgs []string
+	// Append the message of each logged entry
+	for _, m := range testMessages(t, fn) {
+		msgs = append(msgs, m.Msg)
+	}
+	// Return the messages separated by spaces
+	return strings.Join(msgs, " ")
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages.
+import (
+	"io" // io
+	"os" // os
+)
+
+// Sink is a logging output receiving each entry. It receives the entry and its
+// encoding in the format of the logger without newline. A Sink must be safe for
+// concurrent use. A Sink is set on a logger with SetSink.
+type Sink interface {
+	Log(e *Entry, line []byte) error // log entry e encoded as line
+	Close() error                    // flush pending entries and release resources
+}
+
+// SetSink sets the logging output to sink s. The previous sink, if any, is closed. The
+// previous output file is not closed. If s is nil, logging is set to Stdout. It returns
+// an error, if closing the previous sink fails.
+func (l *Logger) SetSink(s Sink) error {
+	// Set logging to Stdout, if s is nil
+	if s == nil {
+		return l.setOutput(os.Stdout, nil, nil)
+	}
+	// Discard logging to the writer and set s as sink
+	return l.setOutput(io.Discard, nil, s)
+}
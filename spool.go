@@ -0,0 +1,397 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages, tserr and tsfio.
+import (
+	"bufio"         // bufio
+	"bytes"         // bytes
+	"errors"        // errors
+	"fmt"           // fmt
+	"io"            // io
+	"os"            // os
+	"path/filepath" // filepath
+	"sort"          // sort
+	"strings"       // strings
+	"sync/atomic"   // atomic
+	"time"          // time
+
+	"github.com/thorstenrie/tserr" // tserr
+	"github.com/thorstenrie/tsfio" // tsfio
+)
+
+// Defaults for spools
+const (
+	// File extension of spool segments
+	spoolExt string = ".spool"
+	// Name of the file holding the read position
+	spoolPos string = "pos"
+	// Maximum size of a spool segment
+	spoolSegment int64 = 1 << 20
+	// Number of read lines between persisting the read position
+	spoolBatch int = 100
+)
+
+// spoolseg is a segment file of a spool.
+type spoolseg struct {
+	seq   uint64 // sequence number
+	size  int64  // size in bytes
+	lines int    // number of unread lines
+}
+
+// spool is a disk-backed queue of lines in segment files in a directory. Lines are appended
+// to the last segment, read from the first segment with peek or batch and removed with next.
+// Read segments are removed. The first segment is kept open for reading. The read position is
+// persisted every spoolBatch lines and on close, so unsent lines are replayed after a restart.
+// If the spool exceeds its maximum size, the oldest segment is dropped. A spool is not safe for
+// concurrent use.
+type spool struct {
+	dir     tsfio.Directory // directory of the segment files
+	max     int64           // maximum total size of the segments in bytes
+	seg     int64           // maximum size of a segment in bytes
+	segs    []spoolseg      // segments in order
+	size    int64           // total size of the segments in bytes
+	off     int64           // read offset in the first segment
+	gen     uint64          // generation of the first unread line, changed if it is read or dropped
+	w       *os.File        // write segment, nil if none is opened
+	f       *os.File        // first segment opened for reading, nil if none is opened
+	r       *bufio.Reader   // reader of f at the read position
+	lines   [][]byte        // unread lines read ahead by peek and batch
+	unsaved int             // number of read lines since persisting the read position
+	dropped *atomic.Uint64  // counter of dropped lines
+}
+
+// newSpool opens the spool in directory dir with maximum size max and counts dropped lines
+// with dropped. The directory is created, if it does not exist. Existing segments are
+// read-only and new lines are appended to a new segment. It returns an error, if max
+// is not positive or the directory cannot be read.
+func newSpool(dir tsfio.Directory, max int64, dropped *atomic.Uint64) (*spool, error) {
+	// Return an error, if max is not positive
+	if max < 1 {
+		return nil, tserr.NotExistent(fmt.Sprintf("spool size %d", max))
+	}
+	// Create the directory, if it does not exist
+	if err := os.MkdirAll(string(dir), 0755); err != nil {
+		return nil, tserr.Op(&tserr.OpArgs{Op: "create spool directory", Fn: string(dir), Err: err})
+	}
+	// Create the spool with a segment size of an eighth of max up to spoolSegment
+	sp := &spool{dir: dir, max: max, seg: max / 8, dropped: dropped}
+	if sp.seg > spoolSegment {
+		sp.seg = spoolSegment
+	}
+	// Retrieve the segment files
+	fs, err := os.ReadDir(string(dir))
+	if err != nil {
+		return nil, tserr.Op(&tserr.OpArgs{Op: "read spool directory", Fn: string(dir), Err: err})
+	}
+	// Add the segments
+	for _, f := range fs {
+		// Skip files other than segments
+		var seq uint64
+		if !strings.HasSuffix(f.Name(), spoolExt) {
+			continue
+		}
+		if _, err := fmt.Sscanf(f.Name(), "%d"+spoolExt, &seq); err != nil {
+			continue
+		}
+		sp.segs = append(sp.segs, spoolseg{seq: seq})
+	}
+	// Sort the segments by sequence number
+	sort.Slice(sp.segs, func(i, j int) bool { return sp.segs[i].seq < sp.segs[j].seq })
+	// Retrieve the read position in the first segment
+	if len(sp.segs) > 0 {
+		if b, err := os.ReadFile(sp.path(spoolPos)); err == nil {
+			var seq uint64
+			if _, err := fmt.Sscanf(string(b), "%d %d", &seq, &sp.off); (err != nil) || (seq != sp.segs[0].seq) {
+				sp.off = 0
+			}
+		}
+	}
+	// Retrieve size and number of unread lines of the segments
+	for i := range sp.segs {
+		// Read the segment
+		b, err := os.ReadFile(sp.name(sp.segs[i].seq))
+		if err != nil {
+			return nil, tserr.Op(&tserr.OpArgs{Op: "read spool segment", Fn: sp.name(sp.segs[i].seq), Err: err})
+		}
+		// Skip read lines of the first segment
+		u := b
+		if (i == 0) && (sp.off <= int64(len(b))) {
+			u = b[sp.off:]
+		}
+		// Keep size and number of unread lines
+		sp.segs[i].size = int64(len(b))
+		sp.segs[i].lines = bytes.Count(u, []byte("\n"))
+		sp.size += sp.segs[i].size
+	}
+	// Return the spool
+	return sp, nil
+}
+
+// empty returns true, if the spool holds no unread lines.
+func (sp *spool) empty() bool {
+	// Return false, if a segment holds unread lines
+	for _, seg := range sp.segs {
+		if seg.lines > 0 {
+			return false
+		}
+	}
+	// Return true
+	return true
+}
+
+// append appends line b terminated by a newline. If the spool exceeds its maximum size,
+// the oldest segments are dropped. If b exceeds the maximum size, b is dropped. It
+// returns an error, if writing fails.
+func (sp *spool) append(b []byte) error {
+	// Drop b, if it exceeds the maximum size
+	if int64(len(b)) > sp.max {
+		sp.dropped.Add(1)
+		return nil
+	}
+	// Drop the oldest segments, until b fits
+	for (sp.size+int64(len(b)) > sp.max) && (len(sp.segs) > 0) {
+		if err := sp.remove(true); err != nil {
+			return err
+		}
+	}
+	// Open a new write segment, if none is opened or the write segment is full
+	if (sp.w == nil) || (sp.segs[len(sp.segs)-1].size+int64(len(b)) > sp.seg) {
+		if err := sp.rotate(); err != nil {
+			return err
+		}
+	}
+	// Append b to the write segment
+	fn := sp.w.Name()
+	n, err := sp.w.Write(b)
+	// Keep size and number of lines of the write segment
+	sp.segs[len(sp.segs)-1].size += int64(n)
+	sp.size += int64(n)
+	// Close the write segment and return an error, if writing fails
+	if err != nil {
+		sp.w.Close()
+		sp.w = nil
+		return tserr.Op(&tserr.OpArgs{Op: "write spool segment", Fn: fn, Err: err})
+	}
+	sp.segs[len(sp.segs)-1].lines++
+	// Return nil
+	return nil
+}
+
+// peek returns the first unread line. It returns nil, if the spool holds no unread lines.
+// Read segments other than the write segment are removed. It returns an error, if reading
+// fails.
+func (sp *spool) peek() ([]byte, error) {
+	// Return the line read before, if any
+	if len(sp.lines) > 0 {
+		return sp.lines[0], nil
+	}
+	for len(sp.segs) > 0 {
+		// Return nil, if the write segment is read
+		if sp.segs[0].lines == 0 {
+			if (len(sp.segs) == 1) && (sp.w != nil) {
+				return nil, nil
+			}
+			// Remove the read segment including an incomplete line from an interrupted write
+			if err := sp.remove(false); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// Open the first segment at the read position, if not opened
+		fn := sp.name(sp.segs[0].seq)
+		if sp.f == nil {
+			if err := sp.open(fn); err != nil {
+				return nil, err
+			}
+		}
+		// Read the next line
+		line, err := sp.r.ReadBytes('\n')
+		// Close the segment and return an error, if reading fails
+		if err != nil {
+			sp.unread()
+			return nil, tserr.Op(&tserr.OpArgs{Op: "read spool segment", Fn: fn, Err: err})
+		}
+		// Keep and return the line
+		sp.lines = append(sp.lines, line)
+		return line, nil
+	}
+	// Return nil, if the spool holds no segments
+	return nil, nil
+}
+
+// batch returns up to n unread lines of the first segment holding unread lines without removing
+// them. The lines are removed with next. It returns nil, if the spool holds no unread lines. It
+// returns an error, if reading fails.
+func (sp *spool) batch(n int) ([][]byte, error) {
+	// Read the first unread line and return nil, if there is none or reading fails
+	if line, err := sp.peek(); (line == nil) || (err != nil) {
+		return nil, err
+	}
+	// Read ahead up to n lines of the first segment
+	for (len(sp.lines) < n) && (len(sp.lines) < sp.segs[0].lines) {
+		line, err := sp.r.ReadBytes('\n')
+		// Close the segment and return an error, if reading fails
+		if err != nil {
+			sp.unread()
+			return nil, tserr.Op(&tserr.OpArgs{Op: "read spool segment", Fn: sp.name(sp.segs[0].seq), Err: err})
+		}
+		sp.lines = append(sp.lines, line)
+	}
+	// Return a copy of up to n lines
+	if len(sp.lines) > n {
+		return append([][]byte(nil), sp.lines[:n]...), nil
+	}
+	return append([][]byte(nil), sp.lines...), nil
+}
+
+// next removes the first line returned by peek or batch. The read position is persisted every
+// spoolBatch lines.
+func (sp *spool) next() {
+	// Advance the read position
+	sp.off += int64(len(sp.lines[0]))
+	sp.segs[0].lines--
+	sp.gen++
+	sp.lines = sp.lines[1:]
+	// Persist the read position, if spoolBatch lines are read
+	if sp.unsaved++; sp.unsaved >= spoolBatch {
+		sp.persist()
+	}
+}
+
+// open opens segment file fn for reading at the read position. It returns an error, if
+// opening or seeking fails.
+func (sp *spool) open(fn string) error {
+	// Open the segment
+	f, err := os.Open(fn)
+	if err != nil {
+		return tserr.Op(&tserr.OpArgs{Op: "open spool segment", Fn: fn, Err: err})
+	}
+	// Close the segment and return an error, if seeking to the read position fails
+	if _, err := f.Seek(sp.off, io.SeekStart); err != nil {
+		f.Close()
+		return tserr.Op(&tserr.OpArgs{Op: "seek spool segment", Fn: fn, Err: err})
+	}
+	// Keep the segment and its reader
+	sp.f, sp.r = f, bufio.NewReader(f)
+	// Return nil
+	return nil
+}
+
+// unread closes the segment opened for reading, if any, and discards the lines read ahead.
+func (sp *spool) unread() {
+	// Close the segment, if opened
+	if sp.f != nil {
+		sp.f.Close()
+	}
+	// Reset the segment, its reader and the lines read ahead
+	sp.f, sp.r, sp.lines = nil, nil, nil
+}
+
+// close closes the write segment, removes read segments and persists the read position. It
+// returns an error, if closing or removing fails.
+func (sp *spool) close() error {
+	// Close the segment opened for reading, if any
+	sp.unread()
+	// err holds the error of closing the write segment
+	var err error
+	// Close the write segment, if opened
+	if sp.w != nil {
+		if e := sp.w.Close(); e != nil {
+			err = tserr.Op(&tserr.OpArgs{Op: "close spool segment", Fn: string(sp.dir), Err: e})
+		}
+		sp.w = nil
+	}
+	// Remove read segments
+	for (len(sp.segs) > 0) && (sp.segs[0].lines == 0) && (err == nil) {
+		err = sp.remove(false)
+	}
+	// Persist the read position
+	sp.persist()
+	// Return the error, if any
+	return err
+}
+
+// rotate closes the write segment and opens a new write segment. It returns an error,
+// if closing or opening fails.
+func (sp *spool) rotate() error {
+	// Close the write segment, if opened
+	if sp.w != nil {
+		err := sp.w.Close()
+		sp.w = nil
+		// Return an error, if closing fails
+		if err != nil {
+			return tserr.Op(&tserr.OpArgs{Op: "close spool segment", Fn: string(sp.dir), Err: err})
+		}
+	}
+	// Retrieve the next sequence number
+	seq := uint64(time.Now().UnixNano())
+	if l := len(sp.segs); (l > 0) && (seq <= sp.segs[l-1].seq) {
+		seq = sp.segs[l-1].seq + 1
+	}
+	// Open the new write segment
+	fn := sp.name(seq)
+	f, err := tsfio.OpenFile(tsfio.Filename(fn))
+	// Return an error, if opening fails
+	if err != nil {
+		return tserr.Op(&tserr.OpArgs{Op: "open spool segment", Fn: fn, Err: err})
+	}
+	// Keep the new write segment
+	sp.w = f
+	sp.segs = append(sp.segs, spoolseg{seq: seq})
+	// Return nil
+	return nil
+}
+
+// remove removes the first segment. If drop is true, its unread lines are counted as
+// dropped. It returns an error, if removing fails.
+func (sp *spool) remove(drop bool) error {
+	// Close the write segment, if it is the first segment
+	if (len(sp.segs) == 1) && (sp.w != nil) {
+		sp.w.Close()
+		sp.w = nil
+	}
+	// Close the segment, if opened for reading
+	sp.unread()
+	// Count unread lines as dropped, if requested
+	if drop {
+		sp.dropped.Add(uint64(sp.segs[0].lines))
+	}
+	// Remove the segment file
+	fn := sp.name(sp.segs[0].seq)
+	if err := os.Remove(fn); (err != nil) && !errors.Is(err, os.ErrNotExist) {
+		return tserr.Op(&tserr.OpArgs{Op: "remove spool segment", Fn: fn, Err: err})
+	}
+	// Remove the segment and reset the read position
+	sp.size -= sp.segs[0].size
+	sp.segs = sp.segs[1:]
+	sp.off = 0
+	sp.gen++
+	// Return nil
+	return nil
+}
+
+// persist writes the sequence number of the first segment and the read position to the
+// position file. A failure is ignored, since it only leads to lines being replayed again.
+func (sp *spool) persist() {
+	// Reset the number of read lines since persisting
+	sp.unsaved = 0
+	// Remove the position file, if the spool has no segments
+	if len(sp.segs) == 0 {
+		os.Remove(sp.path(spoolPos))
+		return
+	}
+	// Write the position file
+	os.WriteFile(sp.path(spoolPos), []byte(fmt.Sprintf("%d %d", sp.segs[0].seq, sp.off)), 0644)
+}
+
+// name returns the path of the segment with sequence number seq.
+func (sp *spool) name(seq uint64) string {
+	return sp.path(fmt.Sprintf("%020d%s", seq, spoolExt))
+}
+
+// path returns the path of file fn in the spool directory.
+func (sp *spool) path(fn string) string {
+	return filepath.Join(string(sp.dir), fn)
+}
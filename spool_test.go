@@ -0,0 +1,315 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages, tserr and tsfio.
+import (
+	"fmt"               // fmt
+	"io"                // io
+	"net"               // net
+	"net/http"          // http
+	"net/http/httptest" // httptest
+	"os"                // os
+	"path/filepath"     // filepath
+	"strings"           // strings
+	"sync/atomic"       // atomic
+	"testing"           // testing
+	"time"              // time
+
+	"github.com/thorstenrie/tserr" // tserr
+	"github.com/thorstenrie/tsfio" // tsfio
+)
+
+// TestSpoolReplay logs three lines to an unreachable unix socket with a spool and closes the sink.
+// Then, it logs a fourth line with a new sink using the same spool after the socket is listening.
+// The test fails if the socket does not receive all lines in order or the spool is not empty.
+func TestSpoolReplay(t *testing.T) {
+	// Create the temporary directory d for the socket and the spool
+	d := tmpDir(t)
+	p, sd := filepath.Join(string(d), "socket"), tsfio.Directory(filepath.Join(string(d), "spool"))
+	// Log three lines to the unreachable socket and close the sink
+	testSpool(t, p, sd, 1<<20, "1", "2", "3")
+	// Record an error, if the spool is empty
+	if n := testSpoolSize(t, sd); n == 0 {
+		t.Error(tserr.NilFailed("spool"))
+	}
+	// Listen on the socket
+	ln, err := net.Listen("unix", p)
+	// Stop execution, if listening fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "listen", Fn: p, Err: err}))
+	}
+	// Log a fourth line with a new sink and close the sink
+	testSpool(t, p, sd, 1<<20, "4")
+	// Accept the connection of the sink
+	c, err := ln.Accept()
+	// Stop execution, if accepting fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "accept", Fn: p, Err: err}))
+	}
+	// Read the lines
+	b, err := io.ReadAll(c)
+	// Stop execution, if reading fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "read", Fn: p, Err: err}))
+	}
+	// Close the connection and the socket
+	c.Close()
+	ln.Close()
+	// Record an error, if the lines are not received in order
+	if string(b) != "1\n2\n3\n4\n" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "1\n2\n3\n4\n", Y: string(b)}))
+	}
+	// Record an error, if the spool is not empty
+	if n := testSpoolSize(t, sd); n != 0 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "spool size", Actual: n, Want: 0}))
+	}
+	// Remove d with the socket and the spool
+	if err := os.RemoveAll(string(d)); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "RemoveAll", Fn: string(d), Err: err}))
+	}
+}
+
+// TestSpoolMax logs lines to an unreachable unix socket with a spool of 256 bytes. The
+// test fails if the spool exceeds 256 bytes or no lines are dropped.
+func TestSpoolMax(t *testing.T) {
+	// Create the temporary directory d for the socket and the spool
+	d := tmpDir(t)
+	p, sd := filepath.Join(string(d), "socket"), tsfio.Directory(filepath.Join(string(d), "spool"))
+	// Create lines of 20 bytes
+	lines := make([]string, 50)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%019d", i)
+	}
+	// Log the lines with a spool of 256 bytes
+	s := testSpool(t, p, sd, 256, lines...)
+	// Record an error, if the spool exceeds 256 bytes
+	if n := testSpoolSize(t, sd); n > 256 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "spool size", Actual: n, Want: 256}))
+	}
+	// Record an error, if no lines were dropped
+	if s.Dropped() == 0 {
+		t.Error(tserr.NilFailed("Dropped"))
+	}
+	// Remove d with the socket and the spool
+	if err := os.RemoveAll(string(d)); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "RemoveAll", Fn: string(d), Err: err}))
+	}
+}
+
+// TestSpoolPeek appends lines to a spool and reads them with peek and next. The test fails
+// if the lines are not read in order or the read position is persisted before spoolBatch
+// lines are read.
+func TestSpoolPeek(t *testing.T) {
+	// Open the spool sp in a temporary directory
+	var dropped atomic.Uint64
+	sd := tsfio.Directory(filepath.Join(string(tmpDir(t)), "spool"))
+	sp, err := newSpool(sd, 1<<20, &dropped)
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New spool", Fn: string(sd), Err: err}))
+	}
+	// Append and read lines alternately
+	for _, line := range []string{"1\n", "2\n", "3\n"} {
+		if err := sp.append([]byte(line)); err != nil {
+			t.Fatal(tserr.Op(&tserr.OpArgs{Op: "append", Fn: string(sd), Err: err}))
+		}
+		// Peek twice and record an error, if the line does not match
+		for i := 0; i < 2; i++ {
+			if b, err := sp.peek(); (err != nil) || (string(b) != line) {
+				t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: line, Y: string(b)}))
+			}
+		}
+		sp.next()
+	}
+	// Record an error, if the spool holds unread lines
+	if b, err := sp.peek(); (err != nil) || (b != nil) || !sp.empty() {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "", Y: string(b)}))
+	}
+	// Record an error, if the read position is persisted
+	if _, err := os.Stat(sp.path(spoolPos)); err == nil {
+		t.Error(tserr.NilFailed("read position"))
+	}
+	// Record an error, if closing fails or the spool is not empty after closing
+	if err := sp.close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "close", Fn: string(sd), Err: err}))
+	}
+	if n := testSpoolSize(t, sd); n != 0 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "spool size", Actual: n, Want: 0}))
+	}
+	// Remove the spool directory
+	if err := os.RemoveAll(filepath.Dir(string(sd))); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "RemoveAll", Fn: string(sd), Err: err}))
+	}
+}
+
+// TestSpoolHTTP logs three lines to an HTTP sink with a spool and a batch size of two to a
+// test server, which fails the first two requests. The test fails if the lines are not posted
+// in order after the outage, lines are dropped or the spool is not empty.
+func TestSpoolHTTP(t *testing.T) {
+	// Create the test server, which fails the first two requests
+	h := &httprec{status: func(n int) int {
+		if n <= 2 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	}}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	// Create the HTTP sink s with a spool in the temporary directory d without retries
+	d := tmpDir(t)
+	sd := tsfio.Directory(filepath.Join(string(d), "spool"))
+	s, err := NewHTTPSink(&HTTPArgs{URL: srv.URL, BatchSize: 2, Interval: 10 * time.Millisecond, Retries: NoRetry, Spool: sd})
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New HTTP sink", Fn: srv.URL, Err: err}))
+	}
+	// Log the lines
+	for _, line := range []string{"a", "b", "c"} {
+		if err := s.Log(nil, []byte(line)); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Log", Fn: srv.URL, Err: err}))
+		}
+	}
+	// Wait up to one second for the lines to be posted after the outage
+	for i := 0; i < 100; i++ {
+		if _, lines := h.requests(); len(lines) >= 3 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Close the sink
+	if err := s.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: srv.URL, Err: err}))
+	}
+	// Record an error, if the lines are not posted in order
+	if _, lines := h.requests(); strings.Join(lines, ",") != "a,b,c" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "a,b,c", Y: strings.Join(lines, ",")}))
+	}
+	// Record an error, if lines were dropped
+	if n := s.Dropped(); n != 0 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "Dropped", Actual: int64(n), Want: 0}))
+	}
+	// Record an error, if the spool is not empty
+	if n := testSpoolSize(t, sd); n != 0 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "spool size", Actual: n, Want: 0}))
+	}
+	// Remove d with the spool
+	if err := os.RemoveAll(string(d)); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "RemoveAll", Fn: string(d), Err: err}))
+	}
+}
+
+// TestSpoolHTTPReplay logs two lines to an HTTP sink with a spool, whose test server fails
+// all requests, and closes the sink. Then, it creates a new sink using the same spool for
+// a test server accepting all requests. The test fails if the lines are not kept in the
+// spool or are not posted by the new sink.
+func TestSpoolHTTPReplay(t *testing.T) {
+	// Create the test servers, which fail and accept all requests
+	hf := &httprec{status: func(int) int { return http.StatusServiceUnavailable }}
+	ho := &httprec{status: func(int) int { return http.StatusOK }}
+	srvf, srvo := httptest.NewServer(hf), httptest.NewServer(ho)
+	defer srvf.Close()
+	defer srvo.Close()
+	// Create the temporary directory d for the spool
+	d := tmpDir(t)
+	sd := tsfio.Directory(filepath.Join(string(d), "spool"))
+	// Log the lines to the failing test server and close the sink
+	s, err := NewHTTPSink(&HTTPArgs{URL: srvf.URL, Retries: NoRetry, Spool: sd})
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New HTTP sink", Fn: srvf.URL, Err: err}))
+	}
+	for _, line := range []string{"a", "b"} {
+		if err := s.Log(nil, []byte(line)); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Log", Fn: srvf.URL, Err: err}))
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: srvf.URL, Err: err}))
+	}
+	// Record an error, if the lines are not kept in the spool
+	if n := testSpoolSize(t, sd); n == 0 {
+		t.Error(tserr.NilFailed("spool size"))
+	}
+	// Post the spooled lines to the accepting test server with a new sink
+	if s, err = NewHTTPSink(&HTTPArgs{URL: srvo.URL, Spool: sd}); err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New HTTP sink", Fn: srvo.URL, Err: err}))
+	}
+	if err := s.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: srvo.URL, Err: err}))
+	}
+	// Record an error, if the lines are not posted in order
+	if _, lines := ho.requests(); strings.Join(lines, ",") != "a,b" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "a,b", Y: strings.Join(lines, ",")}))
+	}
+	// Remove d with the spool
+	if err := os.RemoveAll(string(d)); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "RemoveAll", Fn: string(d), Err: err}))
+	}
+}
+
+// TestSpoolErr creates spooled network sinks with an unsupported network and a spool
+// size of zero. The test fails if NewSpoolNetSink does not return an error.
+func TestSpoolErr(t *testing.T) {
+	// Retrieve a spool directory
+	sd := tsfio.Directory(filepath.Join(os.TempDir(), "tslog_spool"))
+	// Record an error, if NewSpoolNetSink returns nil for an unsupported network
+	if _, err := NewSpoolNetSink("ip", "127.0.0.1", sd, 1); err == nil {
+		t.Error(tserr.NilFailed("New spool network sink"))
+	}
+	// Record an error, if NewSpoolNetSink returns nil for a spool size of zero
+	if _, err := NewSpoolNetSink("tcp", "127.0.0.1:0", sd, 0); err == nil {
+		t.Error(tserr.NilFailed("New spool network sink"))
+	}
+}
+
+// testSpool creates a network sink for unix socket p with spool directory sd of size max
+// and logs lines. It closes the sink afterwards and returns it.
+func testSpool(t *testing.T, p string, sd tsfio.Directory, max int64, lines ...string) *NetSink {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Create the network sink s with a spool
+	s, err := NewSpoolNetSink("unix", p, sd, max)
+	// Stop execution, if creating the sink fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New spool network sink", Fn: p, Err: err}))
+	}
+	// Log the lines
+	for _, line := range lines {
+		if err := s.Log(nil, []byte(line)); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Log", Fn: p, Err: err}))
+		}
+	}
+	// Close the sink
+	if err := s.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: p, Err: err}))
+	}
+	// Return the sink
+	return s
+}
+
+// testSpoolSize returns the total size of the segment files in spool directory sd.
+func testSpoolSize(t *testing.T, sd tsfio.Directory) int64 {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Retrieve the segment files
+	fs, err := filepath.Glob(filepath.Join(string(sd), "*"+spoolExt))
+	// Stop execution, if retrieving the segment files fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "Glob", Fn: string(sd), Err: err}))
+	}
+	// n holds the total size
+	var n int64
+	// Add the size of each segment file
+	for _, f := range fs {
+		s, err := tsfio.FileSize(tsfio.Filename(f))
+		if err != nil {
+			t.Fatal(tserr.Op(&tserr.OpArgs{Op: "FileSize", Fn: f, Err: err}))
+		}
+		n += s
+	}
+	// Return the total size
+	return n
+}
@@ -0,0 +1,306 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"fmt"           // fmt
+	"net"           // net
+	"os"            // os
+	"path/filepath" // filepath
+	"sort"          // sort
+	"strings"       // strings
+	"sync"          // sync
+	"time"          // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// Syslog facilities
+const (
+	FacilityUser   int = 1  // user-level messages
+	FacilityDaemon int = 3  // system daemons
+	FacilityLocal0 int = 16 // local use 0
+	FacilityLocal1 int = 17 // local use 1
+	FacilityLocal2 int = 18 // local use 2
+	FacilityLocal3 int = 19 // local use 3
+	FacilityLocal4 int = 20 // local use 4
+	FacilityLocal5 int = 21 // local use 5
+	FacilityLocal6 int = 22 // local use 6
+	FacilityLocal7 int = 23 // local use 7
+)
+
+// SD-ID of the RFC 5424 structured data element holding the fields
+const SyslogSDID string = "tslog@32473"
+
+// Defaults for syslog
+const (
+	// Timeout for connecting to the syslog server
+	syslogTimeout time.Duration = 5 * time.Second
+	// Timestamp layout for RFC 5424
+	rfc5424Layout string = "2006-01-02T15:04:05.000000Z07:00"
+	// Timestamp layout for RFC 3164
+	rfc3164Layout string = "Jan _2 15:04:05"
+	// Maximum length of the app name
+	syslogAppLen int = 48
+	// Maximum length of a structured data parameter name
+	syslogParamLen int = 32
+)
+
+// syslogSockets holds the paths of the local syslog socket on common systems.
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// severities holds the syslog severity of each log level, indexed by the log level.
+var severities = [...]int{
+	TraceLevel: 7, // debug
+	DebugLevel: 7, // debug
+	InfoLevel:  6, // informational
+	WarnLevel:  4, // warning
+	ErrorLevel: 3, // error
+	FatalLevel: 2, // critical
+}
+
+// SyslogArgs contains the configuration of a SyslogSink.
+type SyslogArgs struct {
+	Network  string // "unixgram", "unix", "udp" or "tcp", empty for the local syslog socket
+	Address  string // address of the syslog server, empty for the local syslog socket
+	Facility int    // facility from 0 (kern) to 23 (local7)
+	AppName  string // app name, empty for the program name
+	RFC3164  bool   // use the BSD syslog format of RFC 3164 instead of RFC 5424
+}
+
+// SyslogSink is a Sink writing entries to syslog. Log levels are mapped to syslog
+// severities from debug for Trace level to critical for Fatal level. With RFC 5424,
+// fields are written as structured data element with SD-ID SyslogSDID. With RFC 3164,
+// fields are not written. On stream connections, RFC 5424 messages are framed by
+// octet counting and RFC 3164 messages are terminated by a newline. If writing fails,
+// the connection is re-established once to the network and address resolved when the
+// sink was created.
+type SyslogSink struct {
+	mu      sync.Mutex // mutex for the connection
+	args    SyslogArgs // configuration
+	network string     // resolved network, fixed after NewSyslogSink
+	address string     // resolved address, fixed after NewSyslogSink
+	host    string     // hostname
+	pid     int        // process id
+	conn    net.Conn   // connection to the syslog server
+}
+
+// NewSyslogSink creates a new SyslogSink with configuration a and connects to the syslog
+// server. It returns an error, if a is nil, the facility is not defined or connecting fails.
+func NewSyslogSink(a *SyslogArgs) (*SyslogSink, error) {
+	// Return an error, if a is nil
+	if a == nil {
+		return nil, tserr.NilPtr()
+	}
+	// Return an error, if the facility is not defined
+	if (a.Facility < 0) || (a.Facility > FacilityLocal7) {
+		return nil, tserr.NotExistent(fmt.Sprintf("syslog facility %d", a.Facility))
+	}
+	// Create the sink with a copy of a
+	s := &SyslogSink{args: *a, network: a.Network, address: a.Address, host: "-", pid: os.Getpid()}
+	// Retrieve the hostname
+	if h, err := os.Hostname(); (err == nil) && (h != "") {
+		s.host = h
+	}
+	// Set the app name to the program name, if empty
+	if s.args.AppName == "" {
+		s.args.AppName = filepath.Base(os.Args[0])
+	}
+	// Sanitize the app name
+	s.args.AppName = sanitize(s.args.AppName, syslogAppLen)
+	// Connect to the syslog server
+	if err := s.dial(); err != nil {
+		return nil, err
+	}
+	// Return the sink
+	return s, nil
+}
+
+// Log writes entry e to syslog. It returns an error, if writing fails after re-establishing
+// the connection.
+func (s *SyslogSink) Log(e *Entry, _ []byte) error {
+	// Format the syslog message
+	msg := s.format(e)
+	// Lock the connection
+	s.mu.Lock()
+	// Unlock the connection on return
+	defer s.mu.Unlock()
+	// Write the message, if connected
+	if s.conn != nil {
+		if _, err := s.conn.Write(msg); err == nil {
+			return nil
+		}
+		// Close the failed connection
+		s.conn.Close()
+		s.conn = nil
+	}
+	// Re-establish the connection
+	if err := s.dial(); err != nil {
+		return err
+	}
+	// Write the message
+	if _, err := s.conn.Write(msg); err != nil {
+		return tserr.Op(&tserr.OpArgs{Op: "write to syslog", Fn: s.address, Err: err})
+	}
+	// Return nil
+	return nil
+}
+
+// Close closes the connection to the syslog server.
+func (s *SyslogSink) Close() error {
+	// Lock the connection
+	s.mu.Lock()
+	// Unlock the connection on return
+	defer s.mu.Unlock()
+	// Return nil, if not connected
+	if s.conn == nil {
+		return nil
+	}
+	// Close the connection
+	err := s.conn.Close()
+	s.conn = nil
+	// Return an error, if closing fails
+	if err != nil {
+		return tserr.Op(&tserr.OpArgs{Op: "close syslog", Fn: s.address, Err: err})
+	}
+	// Return nil
+	return nil
+}
+
+// dial connects to the syslog server. If the network is not resolved yet, it connects to the
+// address or to the local syslog socket via unixgram or unix and keeps the network and path
+// that succeeded. It returns an error, if connecting fails.
+func (s *SyslogSink) dial() error {
+	// Connect to the syslog server, if the network is resolved
+	if s.network != "" {
+		// Connect to the address
+		c, err := net.DialTimeout(s.network, s.address, syslogTimeout)
+		// Return an error, if connecting fails
+		if err != nil {
+			return tserr.Op(&tserr.OpArgs{Op: "connect to syslog", Fn: s.address, Err: err})
+		}
+		// Keep the connection
+		s.conn = c
+		// Return nil
+		return nil
+	}
+	// Retrieve the paths of the local syslog socket
+	paths := syslogSockets
+	if s.args.Address != "" {
+		paths = []string{s.args.Address}
+	}
+	// err holds the last error
+	var err error
+	// Iterate all paths and networks
+	for _, p := range paths {
+		for _, n := range []string{"unixgram", "unix"} {
+			// Connect to the local syslog socket
+			c, e := net.DialTimeout(n, p, syslogTimeout)
+			// Keep the connection, network and path, if connecting succeeds
+			if e == nil {
+				s.conn, s.network, s.address = c, n, p
+				// Return nil
+				return nil
+			}
+			// Keep the error
+			err = e
+		}
+	}
+	// Return an error, if connecting fails
+	return tserr.Op(&tserr.OpArgs{Op: "connect to local syslog", Fn: strings.Join(paths, ", "), Err: err})
+}
+
+// format returns the syslog message for entry e including framing for stream connections.
+func (s *SyslogSink) format(e *Entry) []byte {
+	// Retrieve the priority from facility and severity
+	pri := s.args.Facility*8 + severity(e.Level)
+	// Retrieve whether the connection is stream oriented
+	stream := (s.network == "tcp") || (s.network == "unix")
+	// Format RFC 3164 message terminated by a newline on stream connections
+	if s.args.RFC3164 {
+		// Format the message
+		msg := fmt.Sprintf("<%d>%s %s %s[%d]: %s", pri, e.Time.Format(rfc3164Layout), s.host, s.args.AppName, s.pid, e.Message)
+		// Terminate message on stream connections
+		if stream {
+			msg += "\n"
+		}
+		// Return the message
+		return []byte(msg)
+	}
+	// Format RFC 5424 message
+	msg := fmt.Sprintf("<%d>1 %s %s %s %d - %s %s", pri, e.Time.Format(rfc5424Layout), s.host, s.args.AppName, s.pid, structured(e.Fields), e.Message)
+	// Frame message by octet counting on stream connections
+	if stream {
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+	// Return the message
+	return []byte(msg)
+}
+
+// severity returns the syslog severity of log level lvl. It returns severity error for
+// undefined levels.
+func severity(lvl int) int {
+	// Return severity error for undefined levels
+	if (lvl < TraceLevel) || (lvl > FatalLevel) {
+		return severities[ErrorLevel]
+	}
+	// Return the severity
+	return severities[lvl]
+}
+
+// structured returns the RFC 5424 structured data element with SD-ID SyslogSDID holding
+// the fields f with sorted parameter names. It returns "-", if f is empty.
+func structured(f map[string]any) string {
+	// Return nil value, if f is empty
+	if len(f) == 0 {
+		return "-"
+	}
+	// Retrieve sorted keys of f
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	// b holds the structured data element
+	var b strings.Builder
+	b.WriteString("[" + SyslogSDID)
+	// Escape '"', '\' and ']' in parameter values
+	esc := strings.NewReplacer(`"`, `\"`, `\`, `\\`, `]`, `\]`)
+	// Iterate all fields
+	for _, k := range keys {
+		// Sanitize the parameter name
+		n := sanitize(strings.NewReplacer("=", "", "]", "", `"`, "").Replace(k), syslogParamLen)
+		// Skip fields with empty parameter name
+		if (n == "") || (n == "-") {
+			continue
+		}
+		// Append the parameter
+		fmt.Fprintf(&b, ` %s="%s"`, n, esc.Replace(fmt.Sprint(f[k])))
+	}
+	// Return the structured data element
+	return b.String() + "]"
+}
+
+// sanitize removes non-printable and non-ASCII characters and spaces from s and truncates
+// it to n bytes. It returns "-", if the result is empty.
+func sanitize(s string, n int) string {
+	// Remove all characters except printable ASCII characters
+	s = strings.Map(func(r rune) rune {
+		if (r < 33) || (r > 126) {
+			return -1
+		}
+		return r
+	}, s)
+	// Truncate s to n bytes
+	if len(s) > n {
+		s = s[:n]
+	}
+	// Return "-", if s is empty
+	if s == "" {
+		return "-"
+	}
+	// Return s
+	return s
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"errors"        // errors
+	"fmt"           // fmt
+	"io"            // io
+	"net"           // net
+	"os"            // os
+	"path/filepath" // filepath
+	"strconv"       // strconv
+	"strings"       // strings
+	"testing"       // testing
+	"time"          // time
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// TestSyslogUnixgram logs an entry with a field to a local unix datagram socket in RFC 5424
+// format. The test fails if the received message does not contain the expected priority,
+// app name, structured data and message.
+func TestSyslogUnixgram(t *testing.T) {
+	// Create the temporary directory d
+	d := tmpDir(t)
+	// Listen on a unix datagram socket in d
+	p := filepath.Join(string(d), "log")
+	c, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: p, Net: "unixgram"})
+	// Stop execution, if listening fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "listen", Fn: p, Err: err}))
+	}
+	// Log an entry with a field at Info level with facility local0 to the local syslog socket p
+	testSyslog(t, &SyslogArgs{Address: p, Facility: FacilityLocal0, AppName: "test app"}, InfoLevel)
+	// Receive the message
+	msg := testReceive(t, c)
+	// Close the socket and remove d
+	c.Close()
+	os.Remove(p)
+	rm(t, d)
+	// Iterate expected parts of the message
+	for _, want := range []string{"<134>1 ", " testapp ", ` [tslog@32473 tenant="a\"b\]"] test`} {
+		// Record an error, if the message does not contain the part
+		if !strings.Contains(msg, want) {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: want, Y: msg}))
+		}
+	}
+}
+
+// TestSyslogReconnect logs to the local syslog socket resolved from an empty network and
+// address, replaces the socket and logs again. The test fails if the sink does not reconnect
+// to the resolved socket path.
+func TestSyslogReconnect(t *testing.T) {
+	// Create the temporary directory d
+	d := tmpDir(t)
+	// Remove d on return
+	defer rm(t, d)
+	// Listen on a unix datagram socket in d
+	p := filepath.Join(string(d), "log")
+	c, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: p, Net: "unixgram"})
+	// Stop execution, if listening fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "listen", Fn: p, Err: err}))
+	}
+	// Use p as the only local syslog socket and restore the sockets on return
+	defer func(paths []string) { syslogSockets = paths }(syslogSockets)
+	syslogSockets = []string{p}
+	// Create the syslog sink s with empty network and address
+	s, err := NewSyslogSink(&SyslogArgs{Facility: FacilityUser})
+	// Stop execution, if creating the sink fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New syslog sink", Fn: p, Err: err}))
+	}
+	// Close the sink on return
+	defer s.Close()
+	// Replace the socket
+	c.Close()
+	os.Remove(p)
+	c, err = net.ListenUnixgram("unixgram", &net.UnixAddr{Name: p, Net: "unixgram"})
+	// Stop execution, if listening fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "listen", Fn: p, Err: err}))
+	}
+	// Close the socket on return
+	defer os.Remove(p)
+	defer c.Close()
+	// Iterate two entries, the first one fails on the stale connection
+	for _, m := range []string{"first", "second"} {
+		// Log the entry
+		if err := s.Log(&Entry{Time: time.Now(), Level: InfoLevel, Message: m}, nil); err != nil {
+			t.Fatal(tserr.Op(&tserr.OpArgs{Op: "Log", Fn: p, Err: err}))
+		}
+	}
+	// Record an error, if the socket does not receive the entries
+	for _, want := range []string{"first", "second"} {
+		if msg := testReceive(t, c); !strings.HasSuffix(msg, want) {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: want, Y: msg}))
+		}
+	}
+}
+
+// TestSyslogUDP logs an entry at Error level to a local UDP socket in RFC 3164 format.
+// The test fails if the received message does not match the expected priority, tag and message.
+func TestSyslogUDP(t *testing.T) {
+	// Listen on a local UDP socket
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	// Stop execution, if listening fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "listen", Fn: "udp", Err: err}))
+	}
+	// Close the socket on return
+	defer c.Close()
+	// Log an entry at Error level with facility local0 in RFC 3164 format
+	testSyslog(t, &SyslogArgs{Network: "udp", Address: c.LocalAddr().String(), Facility: FacilityLocal0, AppName: "test", RFC3164: true}, ErrorLevel)
+	// Receive the message
+	msg := testReceive(t, c.(*net.UDPConn))
+	// Iterate expected parts of the message
+	for _, want := range []string{"<131>", fmt.Sprintf(" test[%d]: test", os.Getpid())} {
+		// Record an error, if the message does not contain the part
+		if !strings.Contains(msg, want) {
+			t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: want, Y: msg}))
+		}
+	}
+}
+
+// TestSyslogTCP logs an entry to a local TCP socket in RFC 5424 format. The test
+// fails if the received message is not framed by octet counting.
+func TestSyslogTCP(t *testing.T) {
+	// Listen on a local TCP socket
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	// Stop execution, if listening fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "listen", Fn: "tcp", Err: err}))
+	}
+	// Close the socket on return
+	defer ln.Close()
+	// Log an entry at Warn level with facility user
+	testSyslog(t, &SyslogArgs{Network: "tcp", Address: ln.Addr().String(), Facility: FacilityUser}, WarnLevel)
+	// Accept the connection of the sink
+	c, err := ln.Accept()
+	// Stop execution, if accepting fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "accept", Fn: "tcp", Err: err}))
+	}
+	// Close the connection on return
+	defer c.Close()
+	// Read the framed message
+	b, err := io.ReadAll(c)
+	// Stop execution, if reading fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "read", Fn: "tcp", Err: err}))
+	}
+	// Split the octet count and the message
+	n, msg, _ := strings.Cut(string(b), " ")
+	// Record an error, if the octet count does not equal the length of the message
+	if l, _ := strconv.Atoi(n); l != len(msg) {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "octet count", Actual: int64(l), Want: int64(len(msg))}))
+	}
+	// Record an error, if the message does not start with the priority
+	if !strings.HasPrefix(msg, "<12>1 ") {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "<12>1 ", Y: msg}))
+	}
+}
+
+// TestSyslogErr creates syslog sinks with an undefined facility and an unreachable
+// address. The test fails if NewSyslogSink does not return an error.
+func TestSyslogErr(t *testing.T) {
+	// Iterate invalid configurations
+	for _, a := range []*SyslogArgs{nil, {Facility: 24}, {Network: "unixgram", Address: filepath.Join(os.TempDir(), "tslog_nonexistent")}} {
+		// Record an error, if NewSyslogSink returns nil
+		if _, err := NewSyslogSink(a); err == nil {
+			t.Error(tserr.NilFailed("New syslog sink"))
+		}
+	}
+}
+
+// testSyslog creates a syslog sink with configuration a and logs an entry with message
+// "test" and a field at level lvl. It closes the logger afterwards.
+func testSyslog(t *testing.T, a *SyslogArgs, lvl int) {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Create the syslog sink s
+	s, err := NewSyslogSink(a)
+	// Stop execution, if creating the sink fails
+	if err != nil {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "New syslog sink", Fn: a.Address, Err: err}))
+	}
+	// Create new logger lg with sink s
+	lg := New()
+	if err := lg.SetSink(s); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set sink", Fn: a.Address, Err: err}))
+	}
+	// Add processor setting a field with characters to be escaped
+	lg.AddProcessor(func(e *Entry) bool {
+		e.SetField("tenant", `a"b]`)
+		return true
+	})
+	// Log the entry
+	if err := testLogger(&testcase{level: lvl, in: "test"}, lg); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Log", Fn: a.Address, Err: err}))
+	}
+	// Close the logger
+	if err := lg.Close(); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Close", Fn: a.Address, Err: err}))
+	}
+}
+
+// testReceive returns the next datagram received on c. Execution stops, if
+// no datagram is received within one second.
+func testReceive(t *testing.T, c net.Conn) string {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Set a read deadline of one second
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	// Read the datagram
+	b := make([]byte, 65536)
+	n, err := c.Read(b)
+	// Stop execution, if reading fails
+	if (err != nil) && !errors.Is(err, io.EOF) {
+		t.Fatal(tserr.Op(&tserr.OpArgs{Op: "read", Fn: c.LocalAddr().String(), Err: err}))
+	}
+	// Return the datagram
+	return string(b[:n])
+}
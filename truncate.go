@@ -0,0 +1,172 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"encoding/json" // json
+	"fmt"           // fmt
+	"unicode/utf8"  // utf8
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// Marker and field for truncated content
+const (
+	// Marker appended to truncated content
+	TruncMarker string = "...[truncated]"
+	// Field holding the original length in bytes of truncated content
+	TruncField string = "truncated"
+)
+
+// limits contains the maximum sizes in bytes of a logger. Zero disables a limit.
+type limits struct {
+	msg   int // maximum size of the message
+	field int // maximum size of a field value
+	line  int // maximum size of the encoded entry including the newline
+}
+
+// SetSizeLimits sets the maximum size in bytes of the message, of each field value
+// and of the encoded entry including the newline. A message or field value exceeding
+// its maximum size is truncated and TruncMarker is appended. The original length is
+// recorded in the field TruncField. If the encoded entry exceeds line, the fields are
+// dropped and the message is truncated further. A limit of zero disables the limit.
+// SetSizeLimits returns an error and keeps the current limits, if a limit is negative or
+// msg and field are not larger than the length of TruncMarker.
+func (l *Logger) SetSizeLimits(msg, field, line int) error {
+	// Iterate the limits for the message and field values
+	for _, m := range []int{msg, field} {
+		// Return an error, if the limit is negative or too small for the marker
+		if (m < 0) || ((m > 0) && (m <= len(TruncMarker))) {
+			return tserr.NotExistent(fmt.Sprintf("size limit %d", m))
+		}
+	}
+	// Return an error, if the line limit is negative
+	if line < 0 {
+		return tserr.NotExistent(fmt.Sprintf("size limit %d", line))
+	}
+	// Set the limits
+	l.out.Lock()
+	l.limits = limits{msg: msg, field: field, line: line}
+	l.out.Unlock()
+	// Return nil
+	return nil
+}
+
+// truncate truncates the message and the field values of e exceeding their maximum
+// size. The fields are copied to keep the fields provided by processors unchanged.
+func (lim *limits) truncate(e *Entry) {
+	// Return, if no limits for the message and field values are set
+	if (lim.msg == 0) && (lim.field == 0) {
+		return
+	}
+	// trunc holds the original length of truncated content
+	trunc := make(map[string]int)
+	// Truncate the message, if it exceeds the maximum size
+	if (lim.msg > 0) && (len(e.Message) > lim.msg) {
+		trunc["message"] = len(e.Message)
+		e.Message = cut(e.Message, lim.msg)
+	}
+	// Truncate field values, if a maximum size is set
+	if (lim.field > 0) && (len(e.Fields) > 0) {
+		// f holds the truncated fields
+		f := make(map[string]any, len(e.Fields))
+		// Iterate all fields
+		for k, v := range e.Fields {
+			// Retrieve the string representation of non-string values
+			s, ok := v.(string)
+			if !ok {
+				b, err := json.Marshal(v)
+				s = string(b)
+				// Keep values, which cannot be encoded
+				if err != nil {
+					s = ""
+				}
+			}
+			// Truncate the value, if it exceeds the maximum size
+			if len(s) > lim.field {
+				trunc[k] = len(s)
+				v = cut(s, lim.field)
+			}
+			// Keep the value
+			f[k] = v
+		}
+		// Set the truncated fields
+		e.Fields = f
+	}
+	// Record the original length of truncated content, if any
+	if len(trunc) > 0 {
+		e.SetField(TruncField, trunc)
+	}
+}
+
+// encode encodes e with encoder enc and ensures the encoded entry including the newline
+// does not exceed the line limit. If it exceeds the limit, the fields are dropped
+// and the message is kept, if it meets the limit, or truncated to the largest size
+// meeting the limit. It returns an error, if encoding fails or the limit cannot be met.
+func (lim *limits) encode(enc Encoder, e *Entry) ([]byte, error) {
+	// Encode e
+	j, err := enc(e)
+	// Return the encoded entry, if encoding fails or the limit is met
+	if (err != nil) || (lim.line == 0) || (len(j) < lim.line) {
+		return j, err
+	}
+	// Retrieve the original message and length of the encoded entry
+	msg, n := e.Message, len(j)
+	// Drop the fields and record the original length of the encoded entry
+	e.Fields = map[string]any{TruncField: map[string]int{"line": n}}
+	// fits encodes e with the message truncated to size and returns true, if the limit is met
+	fits := func(size int) bool {
+		e.Message = cut(msg, size)
+		j, err = enc(e)
+		return (err == nil) && (len(j) < lim.line)
+	}
+	// Return an error, if the limit cannot be met with the marker as message
+	if !fits(0) {
+		// Return the error from encoding, if any
+		if err != nil {
+			return nil, err
+		}
+		// Return an error, if the limit cannot be met
+		return nil, tserr.Op(&tserr.OpArgs{Op: "truncate entry", Fn: cut(msg, 2*len(TruncMarker)), Err: fmt.Errorf("encoded entry exceeds %d bytes", lim.line)})
+	}
+	// Return the encoded entry, if the limit is met with the whole message
+	if fits(len(msg)) {
+		return j, nil
+	}
+	// Search the largest size of the message meeting the limit
+	lo, hi := 0, len(msg)
+	for lo+1 < hi {
+		// Move lo to mid, if the limit is met, otherwise move hi to mid
+		if mid := (lo + hi) / 2; fits(mid) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	// Encode e with the largest size of the message meeting the limit
+	fits(lo)
+	// Return the encoded entry
+	return j, err
+}
+
+// cut truncates s to at most size bytes including TruncMarker at a rune boundary.
+// It returns s, if s does not exceed size.
+func cut(s string, size int) string {
+	// Return s, if it does not exceed size
+	if len(s) <= size {
+		return s
+	}
+	// Retrieve the number of bytes kept from s
+	n := size - len(TruncMarker)
+	if n < 0 {
+		n = 0
+	}
+	// Move to a rune boundary
+	for (n > 0) && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	// Return truncated s with marker
+	return s[:n] + TruncMarker
+}
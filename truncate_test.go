@@ -0,0 +1,145 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages, tserr and tsfio.
+import (
+	"fmt"     // fmt
+	"strings" // strings
+	"testing" // testing
+
+	"github.com/thorstenrie/tserr" // tserr
+	"github.com/thorstenrie/tsfio" // tsfio
+)
+
+// TestSizeLimits logs an oversized message with an oversized field value. The test fails
+// if the message and the field value are not truncated with marker or if the original
+// lengths are not recorded.
+func TestSizeLimits(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Set size limits for the message and field values
+	if err := lg.SetSizeLimits(100, 50, 0); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set size limits", Fn: string(fn), Err: err}))
+	}
+	// Add processor setting an oversized field value and a small field value
+	lg.AddProcessor(func(e *Entry) bool {
+		e.SetField("body", strings.Repeat("b", 500))
+		e.SetField("n", 5)
+		return true
+	})
+	// Log an oversized message
+	if err := lg.Info(strings.Repeat("a", 1000)); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: string(fn), Err: err}))
+	}
+	// Retrieve the logged message
+	m := testMessage1(t, fn)
+	// Record an error, if the message is not truncated with marker
+	if (len(m.Msg) != 100) || !strings.HasSuffix(m.Msg, TruncMarker) {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "Message length", Actual: int64(len(m.Msg)), Want: 100}))
+	}
+	// Record an error, if the field value is not truncated with marker
+	if b := fmt.Sprint(m.Fields["body"]); (len(b) != 50) || !strings.HasSuffix(b, TruncMarker) {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "Field length", Actual: int64(len(b)), Want: 50}))
+	}
+	// Record an error, if the original lengths are not recorded
+	if tr := fmt.Sprint(m.Fields[TruncField]); tr != "map[body:500 message:1000]" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "map[body:500 message:1000]", Y: tr}))
+	}
+}
+
+// TestLineLimit logs an oversized message with a line limit. The test fails if the
+// encoded entry exceeds the line limit.
+func TestLineLimit(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Set the line limit
+	if err := lg.SetSizeLimits(0, 0, 200); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set size limits", Fn: string(fn), Err: err}))
+	}
+	// Log an oversized message with characters to be escaped
+	if err := lg.Info(strings.Repeat("a<\"ä", 1000)); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: string(fn), Err: err}))
+	}
+	// Record an error, if the file exceeds the line limit
+	if s := size(t, fn); s > 200 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "Line length", Actual: s, Want: 200}))
+	}
+	// Retrieve the logged message
+	m := testMessage1(t, fn)
+	// Record an error, if the message is not truncated with marker
+	if !strings.HasSuffix(m.Msg, TruncMarker) {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: TruncMarker, Y: m.Msg}))
+	}
+}
+
+// TestSizeLimitsErr sets invalid size limits and a line limit which cannot be met. The
+// test fails if no error is returned.
+func TestSizeLimitsErr(t *testing.T) {
+	// Iterate invalid size limits
+	for _, lim := range [][3]int{{-1, 0, 0}, {0, len(TruncMarker), 0}, {0, 0, -1}} {
+		// Record an error, if SetSizeLimits returns nil
+		if err := New().SetSizeLimits(lim[0], lim[1], lim[2]); err == nil {
+			t.Error(tserr.NilFailed(fmt.Sprint("Set size limits ", lim)))
+		}
+	}
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Set a line limit, which cannot be met
+	if err := lg.SetSizeLimits(0, 0, 10); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set size limits", Fn: "10", Err: err}))
+	}
+	// Record an error, if Info returns nil
+	if err := lg.Info("test"); err == nil {
+		t.Error(tserr.NilFailed("Info"))
+	}
+	// Record an error, if the output is not empty
+	if s := size(t, fn); s != 0 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "Output size", Actual: s, Want: 0}))
+	}
+	rm(t, fn)
+}
+
+// TestLineLimitFields logs a short message with an oversized field and a line limit. The test
+// fails if the field is not dropped or the message is truncated.
+func TestLineLimitFields(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Set the line limit
+	if err := lg.SetSizeLimits(0, 0, 200); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set size limits", Fn: string(fn), Err: err}))
+	}
+	// Add processor setting an oversized field value
+	lg.AddProcessor(func(e *Entry) bool {
+		e.SetField("body", strings.Repeat("b", 500))
+		return true
+	})
+	// Log a short message
+	if err := lg.Info("test"); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Info", Fn: string(fn), Err: err}))
+	}
+	// Retrieve the logged message
+	m := testMessage1(t, fn)
+	// Record an error, if the message is truncated
+	if m.Msg != "test" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "test", Y: m.Msg}))
+	}
+	// Record an error, if the field is not dropped
+	if _, ok := m.Fields["body"]; ok {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "no body", Y: fmt.Sprint(m.Fields)}))
+	}
+}
+
+// testMessage1 returns the single log message in file fn. It removes file fn afterwards.
+// Execution stops if fn does not contain exactly one log message.
+func testMessage1(t *testing.T, fn tsfio.Filename) logmsg {
+	// Retrieve the logged messages
+	msgs := testMessages(t, fn)
+	// Stop execution, if the number of logged messages is not one
+	if len(msgs) != 1 {
+		t.Fatal(tserr.Equal(&tserr.EqualArgs{Var: "No. lines", Actual: int64(len(msgs)), Want: 1}))
+	}
+	// Return the log message
+	return msgs[0]
+}
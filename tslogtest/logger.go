@@ -0,0 +1,129 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslogtest
+
+// Import standard library packages, tserr and tslog.
+import (
+	"sync"    // sync
+	"testing" // testing
+
+	"github.com/thorstenrie/tserr" // tserr
+	"github.com/thorstenrie/tslog" // tslog
+)
+
+// Option configures a test logger created with New.
+type Option func(*config)
+
+// config contains the configuration of a test logger.
+type config struct {
+	level  int      // minimum log level
+	fail   bool     // fail the test on Error and Fatal entries, which are not expected
+	expect []Filter // filters selecting expected Error and Fatal entries
+}
+
+// testSink is a tslog.Sink writing to a test.
+type testSink struct {
+	mu     sync.Mutex // mutex for the test
+	t      testing.TB // test
+	fail   bool       // fail the test on Error and Fatal entries, which are not expected
+	expect []Filter   // filters selecting expected Error and Fatal entries
+	closed bool       // true, if the sink is closed
+}
+
+// WithLevel sets the minimum log level of the test logger to lvl. The default is Trace level.
+func WithLevel(lvl int) Option {
+	return func(c *config) {
+		c.level = lvl
+	}
+}
+
+// FailOnError fails the test on each Error and Fatal entry, which is not declared as
+// expected with Expect.
+func FailOnError() Option {
+	return func(c *config) {
+		c.fail = true
+	}
+}
+
+// Expect declares Error and Fatal entries selected by any of the filters f as expected, so
+// they do not fail the test with FailOnError.
+func Expect(f ...Filter) Option {
+	return func(c *config) {
+		c.expect = append(c.expect, f...)
+	}
+}
+
+// New creates a new test logger writing each entry through t.Log configured with opts. The
+// output is attributed to test t and shown only if the test fails or runs verbose. The logger
+// is closed with t.Cleanup at the end of the test and entries logged afterwards are discarded.
+func New(t testing.TB, opts ...Option) *tslog.Logger {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Apply the options to the default configuration
+	c := config{level: tslog.TraceLevel}
+	for _, o := range opts {
+		o(&c)
+	}
+	// Create the logger with a sink writing to t
+	s := &testSink{t: t, fail: c.fail, expect: c.expect}
+	l := tslog.New()
+	if err := l.SetLevel(c.level); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set level", Fn: "test logger", Err: err}))
+	}
+	l.SetSink(s)
+	// Close the logger at the end of the test
+	t.Cleanup(func() {
+		l.Close()
+	})
+	// Return the test logger
+	return l
+}
+
+// Log writes entry e encoded as line through t.Log and records an error in the test, if
+// e is an unexpected Error or Fatal entry and FailOnError is set. It always returns nil.
+func (s *testSink) Log(e *tslog.Entry, line []byte) error {
+	// Lock the test
+	s.mu.Lock()
+	// Unlock the test on return
+	defer s.mu.Unlock()
+	// Discard e, if the sink is closed at the end of the test
+	if s.closed {
+		return nil
+	}
+	// Write e through t.Log
+	s.t.Log(string(line))
+	// Record an error, if e is an unexpected Error or Fatal entry
+	if s.fail && (e.Level >= tslog.ErrorLevel) && !s.expected(e) {
+		s.t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "expected log entry", Y: string(line)}))
+	}
+	// Return nil
+	return nil
+}
+
+// Close closes the sink. Subsequent entries are discarded. It always returns nil.
+func (s *testSink) Close() error {
+	// Lock the test
+	s.mu.Lock()
+	// Unlock the test on return
+	defer s.mu.Unlock()
+	// Close the sink
+	s.closed = true
+	// Return nil
+	return nil
+}
+
+// expected returns true, if entry e is selected by any of the filters declared with Expect.
+// The test must be locked.
+func (s *testSink) expected(e *tslog.Entry) bool {
+	// Return true, if a filter selects e
+	for _, f := range s.expect {
+		if f(e) {
+			return true
+		}
+	}
+	// Return false
+	return false
+}
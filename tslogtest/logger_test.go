@@ -0,0 +1,75 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslogtest
+
+// Import standard library packages, tserr and tslog.
+import (
+	"errors"  // errors
+	"fmt"     // fmt
+	"strings" // strings
+	"testing" // testing
+
+	"github.com/thorstenrie/tserr" // tserr
+	"github.com/thorstenrie/tslog" // tslog
+)
+
+// recorder is a testing.TB recording logs, errors and cleanup functions.
+type recorder struct {
+	testing.TB          // test, only for the methods not recorded
+	logs       []string // logged output
+	errs       []string // recorded errors
+	cleanup    []func() // cleanup functions
+}
+
+// Log records the output of args.
+func (r *recorder) Log(args ...any) {
+	r.logs = append(r.logs, fmt.Sprint(args...))
+}
+
+// Error records the error of args.
+func (r *recorder) Error(args ...any) {
+	r.errs = append(r.errs, fmt.Sprint(args...))
+}
+
+// Cleanup records cleanup function f.
+func (r *recorder) Cleanup(f func()) {
+	r.cleanup = append(r.cleanup, f)
+}
+
+// TestNew logs entries with a test logger. The test fails if the entries are not written
+// through t.Log or entries are written after the cleanup.
+func TestNew(t *testing.T) {
+	// Create the test logger l writing to r from Debug level
+	r := &recorder{TB: t}
+	l := New(r, WithLevel(tslog.DebugLevel))
+	// Log a Trace entry below the minimum level and a Debug entry
+	l.Trace("trace")
+	l.Debug("debug")
+	// Execute the cleanup at the end of the test and log an entry afterwards
+	for _, f := range r.cleanup {
+		f()
+	}
+	l.Info("after")
+	// Record an error, if only the Debug entry is not written
+	if (len(r.logs) != 1) || !strings.Contains(r.logs[0], `"message":"debug"`) {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "debug", Y: strings.Join(r.logs, " ")}))
+	}
+}
+
+// TestNewFailOnError logs errors with a test logger failing on errors with an expected
+// entry. The test fails if not exactly the unexpected entry fails the test.
+func TestNewFailOnError(t *testing.T) {
+	// Create the test logger l writing to r, failing on errors and expecting entries with
+	// message "expected"
+	r := &recorder{TB: t}
+	l := New(r, FailOnError(), Expect(Message("expected")))
+	// Log an expected and an unexpected Error entry and a Warn entry
+	l.Error(errors.New("expected"))
+	l.Error(errors.New("unexpected"))
+	l.Warn("warn")
+	// Record an error, if not exactly the unexpected entry fails the test
+	if (len(r.errs) != 1) || !strings.Contains(r.errs[0], "unexpected") {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "unexpected", Y: strings.Join(r.errs, " ")}))
+	}
+}
@@ -0,0 +1,219 @@
+// Package tslogtest provides helpers to assert on the log output of tslog in tests.
+//
+// An observing logger created with NewObserver captures its entries in memory. The
+// captured entries are filtered by level, message and field, awaited from asynchronous
+// code with Wait and asserted with AssertCount. A logger created with New writes
+// through t.Log and optionally fails the test on unexpected Error and Fatal entries.
+//
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslogtest
+
+// Import standard library packages, tserr and tslog.
+import (
+	"fmt"     // fmt
+	"reflect" // reflect
+	"strings" // strings
+	"sync"    // sync
+	"testing" // testing
+	"time"    // time
+
+	"github.com/thorstenrie/tserr" // tserr
+	"github.com/thorstenrie/tslog" // tslog
+)
+
+// Filter selects captured entry e, if it returns true.
+type Filter func(e *tslog.Entry) bool
+
+// Observed holds the entries captured by an observing logger. It is a tslog.Sink and
+// safe for concurrent use.
+type Observed struct {
+	mu      sync.Mutex    // mutex for the entries
+	entries []tslog.Entry // captured entries
+	notify  chan struct{} // closed and replaced on each captured entry
+}
+
+// NewObserver creates a new logger at Trace level, which captures its entries in memory,
+// and the Observed holding the captured entries.
+func NewObserver() (*tslog.Logger, *Observed) {
+	// Create the observed entries
+	o := &Observed{notify: make(chan struct{})}
+	// Create the logger at Trace level with o as sink
+	l := tslog.New()
+	l.SetLevel(tslog.TraceLevel)
+	l.SetSink(o)
+	// Return the logger and the observed entries
+	return l, o
+}
+
+// Level returns a Filter selecting entries at level lvl.
+func Level(lvl int) Filter {
+	return func(e *tslog.Entry) bool {
+		return e.Level == lvl
+	}
+}
+
+// Message returns a Filter selecting entries with message msg.
+func Message(msg string) Filter {
+	return func(e *tslog.Entry) bool {
+		return e.Message == msg
+	}
+}
+
+// Contains returns a Filter selecting entries with a message containing sub.
+func Contains(sub string) Filter {
+	return func(e *tslog.Entry) bool {
+		return strings.Contains(e.Message, sub)
+	}
+}
+
+// Field returns a Filter selecting entries with field key set to value v.
+func Field(key string, v any) Filter {
+	return func(e *tslog.Entry) bool {
+		f, ok := e.Fields[key]
+		return ok && reflect.DeepEqual(f, v)
+	}
+}
+
+// Log captures a copy of entry e. It always returns nil.
+func (o *Observed) Log(e *tslog.Entry, line []byte) error {
+	// Copy e and its fields
+	c := *e
+	if e.Fields != nil {
+		c.Fields = make(map[string]any, len(e.Fields))
+		for k, v := range e.Fields {
+			c.Fields[k] = v
+		}
+	}
+	// Lock the entries
+	o.mu.Lock()
+	// Unlock the entries on return
+	defer o.mu.Unlock()
+	// Capture the copy
+	o.entries = append(o.entries, c)
+	// Notify waiting callers
+	close(o.notify)
+	o.notify = make(chan struct{})
+	// Return nil
+	return nil
+}
+
+// Close always returns nil. The captured entries are kept.
+func (o *Observed) Close() error {
+	return nil
+}
+
+// All returns copies of all captured entries in the order they were logged.
+func (o *Observed) All() []tslog.Entry {
+	return o.Filter()
+}
+
+// Filter returns copies of the captured entries selected by all filters f in the order
+// they were logged.
+func (o *Observed) Filter(f ...Filter) []tslog.Entry {
+	// Lock the entries
+	o.mu.Lock()
+	// Unlock the entries on return
+	defer o.mu.Unlock()
+	// Return the selected entries
+	return o.filter(f)
+}
+
+// Count returns the number of captured entries selected by all filters f.
+func (o *Observed) Count(f ...Filter) int {
+	return len(o.Filter(f...))
+}
+
+// Reset discards all captured entries.
+func (o *Observed) Reset() {
+	// Lock the entries
+	o.mu.Lock()
+	// Unlock the entries on return
+	defer o.mu.Unlock()
+	// Discard the entries
+	o.entries = nil
+}
+
+// Wait waits until at least n captured entries are selected by all filters f, e.g. for
+// entries logged by asynchronous code. It returns the selected entries. It returns the
+// selected entries and an error, if they are less than n after timeout.
+func (o *Observed) Wait(n int, timeout time.Duration, f ...Filter) ([]tslog.Entry, error) {
+	// Stop waiting after timeout
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	for {
+		// Retrieve the selected entries and the notification of the next entry
+		o.mu.Lock()
+		s, notify := o.filter(f), o.notify
+		o.mu.Unlock()
+		// Return the selected entries, if they are at least n
+		if len(s) >= n {
+			return s, nil
+		}
+		// Wait for the next entry or the timeout
+		select {
+		case <-notify:
+		case <-t.C:
+			return s, tserr.Equal(&tserr.EqualArgs{Var: "observed entries", Actual: int64(len(s)), Want: int64(n)})
+		}
+	}
+}
+
+// AssertCount records an error in t, if the number of captured entries selected by all
+// filters f is not exactly n. It returns true, if the number is n.
+func (o *Observed) AssertCount(t testing.TB, n int, f ...Filter) bool {
+	// Panic if t is nil
+	if t == nil {
+		panic("nil pointer")
+	}
+	// Mark the caller as helper
+	t.Helper()
+	// Retrieve the selected entries
+	s := o.Filter(f...)
+	// Return true, if the number is n
+	if len(s) == n {
+		return true
+	}
+	// Record an error with the selected entries
+	t.Error(tserr.Equal(&tserr.EqualArgs{Var: fmt.Sprintf("observed entries %v", messages(s)), Actual: int64(len(s)), Want: int64(n)}))
+	// Return false
+	return false
+}
+
+// filter returns copies of the captured entries selected by all filters f. The entries must be locked.
+func (o *Observed) filter(f []Filter) []tslog.Entry {
+	// s holds the selected entries
+	s := make([]tslog.Entry, 0, len(o.entries))
+	// Append each entry selected by all filters
+	for i := range o.entries {
+		if selected(&o.entries[i], f) {
+			s = append(s, o.entries[i])
+		}
+	}
+	// Return the selected entries
+	return s
+}
+
+// selected returns true, if entry e is selected by all filters f.
+func selected(e *tslog.Entry, f []Filter) bool {
+	// Return false, if a filter does not select e
+	for _, sel := range f {
+		if !sel(e) {
+			return false
+		}
+	}
+	// Return true
+	return true
+}
+
+// messages returns the messages of entries s.
+func messages(s []tslog.Entry) []string {
+	// m holds the messages
+	m := make([]string, len(s))
+	for i := range s {
+		m[i] = s[i].Message
+	}
+	// Return the messages
+	return m
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslogtest
+
+// Import standard library packages, tserr and tslog.
+import (
+	"errors"  // errors
+	"strings" // strings
+	"testing" // testing
+	"time"    // time
+
+	"github.com/thorstenrie/tserr" // tserr
+	"github.com/thorstenrie/tslog" // tslog
+)
+
+// TestObserverFilter logs entries with an observing logger. The test fails if the counts of
+// the entries selected by level, message and field do not match.
+func TestObserverFilter(t *testing.T) {
+	// Create the observing logger l
+	l, o := NewObserver()
+	// Set field tenant for messages starting with "req"
+	l.AddProcessor(func(e *tslog.Entry) bool {
+		if strings.HasPrefix(e.Message, "req") {
+			e.SetField("tenant", "a")
+		}
+		return true
+	})
+	// Log entries
+	l.Debug("start")
+	l.Info("request 1")
+	l.Info("request 2")
+	l.Error(errors.New("failed"))
+	// Assert the counts of the selected entries
+	o.AssertCount(t, 4)
+	o.AssertCount(t, 2, Level(tslog.InfoLevel))
+	o.AssertCount(t, 1, Message("start"))
+	o.AssertCount(t, 2, Contains("request"), Field("tenant", "a"))
+	o.AssertCount(t, 0, Level(tslog.ErrorLevel), Contains("request"))
+	// Record an error, if the entries are not kept in the order they were logged
+	if a := o.All(); a[3].Message != "failed" {
+		t.Error(tserr.NotEqualStr(&tserr.NotEqualStrArgs{X: "failed", Y: a[3].Message}))
+	}
+	// Discard the entries and record an error, if any remain
+	o.Reset()
+	if c := o.Count(); c != 0 {
+		t.Error(tserr.Equal(&tserr.EqualArgs{Var: "count", Actual: int64(c), Want: 0}))
+	}
+}
+
+// TestObserverWait logs an entry from a goroutine. The test fails if Wait does not return
+// the entry or does not return an error after the timeout for a missing entry.
+func TestObserverWait(t *testing.T) {
+	// Create the observing logger l
+	l, o := NewObserver()
+	// Log an entry from a goroutine
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		l.Warn("async")
+	}()
+	// Wait for the entry and record an error, if it is not returned
+	if s, err := o.Wait(1, time.Second, Message("async")); (err != nil) || (len(s) != 1) {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Wait", Fn: "async", Err: err}))
+	}
+	// Record an error, if Wait returns nil for a missing entry
+	if _, err := o.Wait(1, 10*time.Millisecond, Message("missing")); err == nil {
+		t.Error(tserr.NilFailed("Wait"))
+	}
+}
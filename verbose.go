@@ -0,0 +1,209 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"fmt"     // fmt
+	"path"    // path
+	"runtime" // runtime
+	"strconv" // strconv
+	"strings" // strings
+	"sync"    // sync
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// Verbose is returned by V. If the requested verbosity is enabled, Log writes
+// a message at Trace level. Otherwise, Log does nothing. Verbose is
+// designed to be used inline, e.g. l.V(2).Log("message").
+type Verbose struct {
+	l *Logger // logger, nil if the verbosity is disabled
+}
+
+// vmodule contains the parsed per-file verbosity overrides set by SetVModule.
+type vmodule struct {
+	filters []vfilter // filters in the order of the specification
+	max     int       // highest verbosity of all filters
+	cache   sync.Map  // verbosity per program counter of the caller
+}
+
+// vfilter contains a pattern for a source file and the verbosity enabled for it.
+type vfilter struct {
+	pattern string // pattern matched against the file path without .go suffix
+	depth   int    // number of path elements of the pattern
+	v       int    // verbosity for matching files
+}
+
+// Depth of the caller of V in the call stack of vlevel
+const vdepth int = 2
+
+// V returns a Verbose for verbosity level. The verbosity levels are below Trace
+// level. Messages are only logged, if the minimum level is Trace and
+// level is equal to or lower than the verbosity set with SetVerbosity or the
+// verbosity set with SetVModule for the source file of the caller. Determining
+// the caller is only performed, if a vmodule specification is set and
+// level is lower than or equal to its highest verbosity.
+func (l *Logger) V(level int) Verbose {
+	return l.vlevel(level)
+}
+
+// Enabled returns true, if the verbosity of v is enabled, otherwise false.
+func (v Verbose) Enabled() bool {
+	return v.l != nil
+}
+
+// Log logs a message at Trace level, if the verbosity of v is enabled.
+// It returns an error if JSON encoding of msg fails.
+func (v Verbose) Log(msg string) error {
+	// Do nothing, if the verbosity is disabled
+	if v.l == nil {
+		return nil
+	}
+	// Log message at Trace level
+	return v.l.tryLog(TraceLevel, msg)
+}
+
+// SetVerbosity sets the verbosity for V. All verbosity levels equal to or lower
+// than v are logged, if the minimum level is Trace. SetVerbosity returns an error,
+// if v is negative and sets the verbosity to zero.
+func (l *Logger) SetVerbosity(v int) error {
+	// Return an error and set verbosity to zero, if v is negative
+	if v < 0 {
+		// Set verbosity to zero
+		l.verbosity.Store(0)
+		// Return error
+		return tserr.NotExistent(fmt.Sprintf("verbosity %d", v))
+	}
+	// Set verbosity to v
+	l.verbosity.Store(int32(v))
+	// Return nil
+	return nil
+}
+
+// SetVModule sets per-file verbosity overrides. The specification spec is a comma
+// separated list of pattern=N, e.g. "conn=3,net/*=2". A pattern without a
+// slash is matched against the base name of the source file of the caller without
+// the .go suffix. A pattern with a slash is matched against the same number of trailing
+// path elements, which enables matching all files of a package with "package/*". The
+// first matching pattern applies. Patterns use the syntax of path.Match. An empty
+// spec removes all overrides. SetVModule returns an error, if spec is malformed. In
+// that case, the previous overrides are kept.
+func (l *Logger) SetVModule(spec string) error {
+	// Remove all overrides, if spec is empty
+	if strings.TrimSpace(spec) == "" {
+		l.vmodule.Store(nil)
+		// Return nil
+		return nil
+	}
+	// vm holds the parsed specification
+	vm := &vmodule{}
+	// Iterate all comma separated items of spec
+	for _, item := range strings.Split(spec, ",") {
+		// Split item into pattern and verbosity
+		p, n, ok := strings.Cut(strings.TrimSpace(item), "=")
+		// Return an error, if the item does not contain a verbosity
+		if !ok || p == "" {
+			return tserr.NotExistent(fmt.Sprintf("verbosity in vmodule item %q", item))
+		}
+		// Return an error, if the pattern is malformed
+		if _, err := path.Match(p, ""); err != nil {
+			return tserr.Check(&tserr.CheckArgs{F: p, Err: err})
+		}
+		// Parse verbosity
+		v, err := strconv.Atoi(n)
+		// Return an error, if the verbosity is not a non-negative number
+		if err != nil {
+			return tserr.Check(&tserr.CheckArgs{F: n, Err: err})
+		} else if v < 0 {
+			return tserr.NotExistent(fmt.Sprintf("verbosity %d", v))
+		}
+		// Append the filter
+		vm.filters = append(vm.filters, vfilter{pattern: p, depth: strings.Count(p, "/") + 1, v: v})
+		// Update the highest verbosity
+		if v > vm.max {
+			vm.max = v
+		}
+	}
+	// Activate the overrides
+	l.vmodule.Store(vm)
+	// Return nil
+	return nil
+}
+
+// vlevel returns a Verbose for verbosity level, which is enabled according to
+// the verbosity and the vmodule specification. The caller of V is expected
+// at depth vdepth in the call stack.
+func (l *Logger) vlevel(level int) Verbose {
+	// Disabled, if Trace level is not logged
+	if l.minLvl > TraceLevel {
+		return Verbose{}
+	}
+	// Enabled, if level is equal to or lower than the verbosity
+	if level <= int(l.verbosity.Load()) {
+		return Verbose{l: l}
+	}
+	// Retrieve vmodule specification
+	vm := l.vmodule.Load()
+	// Disabled, if there is no specification or level is higher than all overrides
+	if (vm == nil) || (level > vm.max) {
+		return Verbose{}
+	}
+	// Retrieve program counter of the caller
+	var pc [1]uintptr
+	// Disabled, if the caller cannot be retrieved
+	if runtime.Callers(vdepth+1, pc[:]) < 1 {
+		return Verbose{}
+	}
+	// Enabled, if level is equal to or lower than the verbosity for the caller
+	if level <= vm.verbosity(pc[0]) {
+		return Verbose{l: l}
+	}
+	// Disabled
+	return Verbose{}
+}
+
+// verbosity returns the verbosity for the source file containing program counter pc.
+// The result is cached for pc. It returns -1, if no pattern matches.
+func (vm *vmodule) verbosity(pc uintptr) int {
+	// Return cached verbosity, if available
+	if v, ok := vm.cache.Load(pc); ok {
+		return v.(int)
+	}
+	// Retrieve the stack frame of pc
+	fr, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	// Retrieve source file of the frame without .go suffix
+	file := strings.TrimSuffix(fr.File, ".go")
+	// v holds the verbosity, -1 if no pattern matches
+	v := -1
+	// Iterate filters in order of the specification
+	for _, f := range vm.filters {
+		// Return verbosity of the first matching filter
+		if ok, _ := path.Match(f.pattern, trailing(file, f.depth)); ok {
+			v = f.v
+			break
+		}
+	}
+	// Cache verbosity for pc
+	vm.cache.Store(pc, v)
+	// Return verbosity
+	return v
+}
+
+// trailing returns the last n slash separated elements of p.
+func trailing(p string, n int) string {
+	// Iterate path elements from the end of p
+	for i := len(p) - 1; i >= 0; i-- {
+		// Count elements at each slash
+		if p[i] == '/' {
+			n--
+			// Return the trailing elements, if n elements are found
+			if n == 0 {
+				return p[i+1:]
+			}
+		}
+	}
+	// Return p, if it has less than n elements
+	return p
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2023 thorstenrie
+// All Rights Reserved. Use is governed with GNU Affero General Public License v3.0
+// that can be found in the LICENSE file.
+package tslog
+
+// Import standard library packages and tserr.
+import (
+	"testing" // testing
+
+	"github.com/thorstenrie/tserr" // tserr
+)
+
+// TestVerbosity logs at verbosity levels one to three with verbosity set to two.
+// The test fails if messages above verbosity two are logged or if messages at or
+// below verbosity two are not logged.
+func TestVerbosity(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Set verbosity to two
+	if err := lg.SetVerbosity(2); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set verbosity", Fn: string(fn), Err: err}))
+	}
+	// Log at verbosity levels one to three
+	for v := 1; v <= 3; v++ {
+		if err := lg.V(v).Log("test"); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Log verbose", Fn: string(fn), Err: err}))
+		}
+	}
+	// Evaluate the number of logged lines
+	testLines(t, fn, 2)
+}
+
+// TestVerbosityLevel logs at verbosity level zero with minimum level Info.
+// The test fails if the message is logged.
+func TestVerbosityLevel(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Set level to Info
+	if err := lg.SetLevel(InfoLevel); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set level", Fn: string(fn), Err: err}))
+	}
+	// Record an error, if verbosity level zero is enabled
+	if lg.V(0).Enabled() {
+		t.Error(tserr.NilFailed("V(0).Enabled at Info level"))
+	}
+	// Log at verbosity level zero
+	if err := lg.V(0).Log("test"); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Log verbose", Fn: string(fn), Err: err}))
+	}
+	// Evaluate the number of logged lines
+	testLines(t, fn, 0)
+}
+
+// TestVModule enables verbosity three for this source file and for this package
+// with a vmodule specification. The test fails if messages at verbosity three are not
+// logged or if messages are logged with a specification not matching this file.
+func TestVModule(t *testing.T) {
+	// Create new logger lg logging to temporary file fn at Trace level
+	lg, fn := traceLogger(t)
+	// Iterate specifications matching this source file, a pattern for all files of the
+	// directory and a specification not matching this source file
+	for _, spec := range []string{"verbose_test=3", "*/verbose_*=3", "other=3,verbose=3"} {
+		// Set vmodule specification
+		if err := lg.SetVModule(spec); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Set vmodule", Fn: spec, Err: err}))
+		}
+		// Log at verbosity levels three and four
+		for v := 3; v <= 4; v++ {
+			if err := lg.V(v).Log(spec); err != nil {
+				t.Error(tserr.Op(&tserr.OpArgs{Op: "Log verbose", Fn: string(fn), Err: err}))
+			}
+		}
+	}
+	// Remove the vmodule specification
+	if err := lg.SetVModule(""); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set vmodule", Fn: "empty", Err: err}))
+	}
+	// Record an error, if verbosity level three is enabled
+	if lg.V(3).Enabled() {
+		t.Error(tserr.NilFailed("V(3).Enabled without vmodule"))
+	}
+	// Evaluate the number of logged lines
+	testLines(t, fn, 2)
+}
+
+// TestVModuleErr sets malformed vmodule specifications. The test fails if
+// SetVModule does not return an error.
+func TestVModuleErr(t *testing.T) {
+	// Create new logger lg
+	lg := New()
+	// Iterate malformed specifications
+	for _, spec := range []string{"verbose", "=1", "verbose=x", "verbose=-1", "[=1"} {
+		// Record an error, if SetVModule returns nil
+		if err := lg.SetVModule(spec); err == nil {
+			t.Error(tserr.NilFailed("Set vmodule " + spec))
+		}
+	}
+}
+
+// TestSetVerbosityErr sets a negative verbosity. The test fails if SetVerbosity
+// does not return an error.
+func TestSetVerbosityErr(t *testing.T) {
+	// Record an error, if SetVerbosity returns nil
+	if err := SetVerbosity(-1); err == nil {
+		t.Error(tserr.NilFailed("Set verbosity"))
+	}
+}
+
+// TestV logs at verbosity levels with the global predefined standard logger using
+// a vmodule specification for this source file. The test fails if the messages are
+// not logged as expected.
+func TestV(t *testing.T) {
+	// Create the temporary file fn
+	fn := tmp(t)
+	// Set output to temporary file fn
+	if err := SetOutput(fn); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set output", Fn: string(fn), Err: err}))
+	}
+	// Set logging level to Trace
+	if err := SetLevel(TraceLevel); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set level", Fn: string(fn), Err: err}))
+	}
+	// Set vmodule specification for this source file
+	if err := SetVModule("verbose_test=2"); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set vmodule", Fn: string(fn), Err: err}))
+	}
+	// Log at verbosity levels one to three
+	for v := 1; v <= 3; v++ {
+		if err := V(v).Log("test"); err != nil {
+			t.Error(tserr.Op(&tserr.OpArgs{Op: "Log verbose", Fn: string(fn), Err: err}))
+		}
+	}
+	// Remove the vmodule specification
+	if err := SetVModule(""); err != nil {
+		t.Error(tserr.Op(&tserr.OpArgs{Op: "Set vmodule", Fn: "empty", Err: err}))
+	}
+	// Evaluate the number of logged lines
+	testLines(t, fn, 2)
+}